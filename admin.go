@@ -0,0 +1,206 @@
+package simular
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AdminPrefix is the path prefix under which servers started via NewServer
+// expose the admin API. The admin API allows stubs to be managed and
+// inspected over HTTP, for example by end to end tests written in other
+// languages:
+//
+// 		GET    /__simular/stubs       lists registered stubs
+// 		POST   /__simular/stubs       registers a stub from a StubDefinition
+// 		DELETE /__simular/stubs/{id}  removes a stub
+// 		POST   /__simular/reset       resets the transport, as Reset
+// 		GET    /__simular/journal     lists the requests received
+// 		GET    /__simular/uncalled    lists stubs not called as expected, as AllStubsCalled
+const AdminPrefix = "/__simular/"
+
+// StubView is the JSON representation of a registered stub returned by the
+// admin API.
+type StubView struct {
+	ID     int         `json:"id"`
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Calls  int         `json:"calls"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// JournalView is the JSON representation of a journal entry returned by the
+// admin API.
+type JournalView struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Time       time.Time   `json:"time"`
+	StubID     int         `json:"stub_id,omitempty"`
+	StatusCode int         `json:"status,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// UncalledView is the JSON representation of the result of AllStubsCalled
+// returned by the admin API.
+type UncalledView struct {
+	Error string      `json:"error,omitempty"`
+	Stubs []*StubView `json:"stubs"`
+}
+
+// AdminHandler returns an http.Handler serving the admin API for the
+// transport. Paths are expected to begin with AdminPrefix.
+func (m *MockTransport) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, AdminPrefix), "/")
+
+		switch {
+		case path == "stubs" && r.Method == http.MethodGet:
+			m.adminListStubs(w)
+		case path == "stubs" && r.Method == http.MethodPost:
+			m.adminAddStub(w, r)
+		case strings.HasPrefix(path, "stubs/") && r.Method == http.MethodDelete:
+			m.adminDeleteStub(w, strings.TrimPrefix(path, "stubs/"))
+		case path == "reset" && r.Method == http.MethodPost:
+			m.Reset()
+			w.WriteHeader(http.StatusNoContent)
+		case path == "journal" && r.Method == http.MethodGet:
+			m.adminJournal(w)
+		case path == "uncalled" && r.Method == http.MethodGet:
+			m.adminUncalled(w)
+		default:
+			http.Error(w, fmt.Sprintf("Unknown admin endpoint %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+		}
+	})
+}
+
+// adminListStubs writes every registered stub
+func (m *MockTransport) adminListStubs(w http.ResponseWriter) {
+	views := []*StubView{}
+	for _, stub := range m.StubRequests() {
+		views = append(views, newStubView(stub))
+	}
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+// adminAddStub registers a stub from the StubDefinition in the request body
+func (m *MockTransport) adminAddStub(w http.ResponseWriter, r *http.Request) {
+	definition := &StubDefinition{}
+	if err := json.NewDecoder(r.Body).Decode(definition); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid stub definition: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// don't allow clients to read arbitrary files from the server
+	if definition.Response != nil && definition.Response.BodyFile != "" {
+		http.Error(w, "Invalid stub definition: body_file is not supported via the admin API", http.StatusBadRequest)
+		return
+	}
+
+	stub, err := definition.StubRequest()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.RegisterStubRequests(stub)
+
+	writeJSON(w, http.StatusCreated, newStubView(stub))
+}
+
+// adminDeleteStub removes the stub with the given id
+func (m *MockTransport) adminDeleteStub(w http.ResponseWriter, rawID string) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid stub id %s", rawID), http.StatusBadRequest)
+		return
+	}
+
+	for _, stub := range m.StubRequests() {
+		if stub.id == id && m.RemoveStubRequest(stub) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	http.Error(w, fmt.Sprintf("Unknown stub id %d", id), http.StatusNotFound)
+}
+
+// adminJournal writes every entry in the journal
+func (m *MockTransport) adminJournal(w http.ResponseWriter) {
+	views := []*JournalView{}
+
+	for _, entry := range m.Journal() {
+		view := &JournalView{
+			Method:     entry.Method,
+			URL:        entry.URL.String(),
+			Header:     entry.Header,
+			Body:       string(entry.Body),
+			Time:       entry.Time,
+			StatusCode: entry.StatusCode,
+		}
+
+		if entry.Stub != nil {
+			view.StubID = entry.Stub.id
+		}
+
+		if entry.Err != nil {
+			view.Error = entry.Err.Error()
+		}
+
+		views = append(views, view)
+	}
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+// adminUncalled writes the stubs that have not been called as expected
+func (m *MockTransport) adminUncalled(w http.ResponseWriter) {
+	view := &UncalledView{Stubs: []*StubView{}}
+
+	if err := m.AllStubsCalled(); err != nil {
+		view.Error = err.Error()
+
+		if notCalled, ok := err.(*ErrStubsNotCalled); ok {
+			for _, stub := range notCalled.uncalledStubs {
+				view.Stubs = append(view.Stubs, newStubView(stub))
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, view)
+}
+
+// newStubView returns the JSON representation of the given stub
+func newStubView(stub *StubRequest) *StubView {
+	view := &StubView{
+		ID:     stub.id,
+		Method: stub.Method,
+		URL:    stub.URL,
+		Calls:  stub.CallCount(),
+	}
+
+	if stub.Header != nil {
+		view.Header = *stub.Header
+	}
+
+	if err := stub.verifyCalls(); err != nil {
+		view.Error = err.Error()
+	}
+
+	return view
+}
+
+// writeJSON writes the given value as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(v)
+}
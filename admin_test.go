@@ -0,0 +1,113 @@
+package simular
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// adminRequest makes a request to the admin API, decoding any JSON response
+// into v.
+func adminRequest(t *testing.T, client *http.Client, method, url, body string, v interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil && resp.Header.Get("Content-Type") == "application/json" {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("Unexpected error decoding response: %v", err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func TestAdminAPI(t *testing.T) {
+	transport := NewMockTransport()
+
+	server := NewServer(transport, "https://api.example.com")
+	defer server.Close()
+
+	// use the server's client, as other tests replace http.DefaultTransport
+	client := server.Client()
+
+	admin := server.URL + AdminPrefix
+
+	created := &StubView{}
+	status := adminRequest(t, client, "POST", admin+"stubs", `{"method":"GET","url":"https://api.example.com/status","response":{"status":200,"body":"ok"}}`, created)
+	if status != http.StatusCreated || created.ID == 0 {
+		t.Fatalf("Unexpected response adding stub: %d %#v", status, created)
+	}
+
+	other := &StubView{}
+	adminRequest(t, client, "POST", admin+"stubs", `{"method":"GET","url":"https://api.example.com/other"}`, other)
+
+	if status := adminRequest(t, client, "POST", admin+"stubs", `{"method":"GET"}`, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected invalid definition to be rejected, got %d", status)
+	}
+
+	if status := adminRequest(t, client, "POST", admin+"stubs", `{"method":"GET","url":"http://x","response":{"body_file":"/etc/passwd"}}`, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected body file to be rejected, got %d", status)
+	}
+
+	stubs := []*StubView{}
+	adminRequest(t, client, "GET", admin+"stubs", "", &stubs)
+	if len(stubs) != 2 {
+		t.Fatalf("Expected 2 stubs, got %d", len(stubs))
+	}
+
+	resp, err := client.Get(server.URL + "/status")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	uncalled := &UncalledView{}
+	adminRequest(t, client, "GET", admin+"uncalled", "", uncalled)
+	if len(uncalled.Stubs) != 1 || uncalled.Stubs[0].ID != other.ID || uncalled.Error == "" {
+		t.Errorf("Unexpected uncalled stubs: %#v", uncalled)
+	}
+
+	journal := []*JournalView{}
+	adminRequest(t, client, "GET", admin+"journal", "", &journal)
+	if len(journal) != 1 || journal[0].StubID != created.ID || journal[0].StatusCode != 200 {
+		t.Errorf("Unexpected journal: %#v", journal)
+	}
+
+	if status := adminRequest(t, client, "DELETE", fmt.Sprintf("%sstubs/%d", admin, other.ID), "", nil); status != http.StatusNoContent {
+		t.Errorf("Unexpected status deleting stub: %d", status)
+	}
+
+	if status := adminRequest(t, client, "DELETE", fmt.Sprintf("%sstubs/%d", admin, other.ID), "", nil); status != http.StatusNotFound {
+		t.Errorf("Unexpected status deleting missing stub: %d", status)
+	}
+
+	uncalled = &UncalledView{}
+	adminRequest(t, client, "GET", admin+"uncalled", "", uncalled)
+	if len(uncalled.Stubs) != 0 || uncalled.Error != "" {
+		t.Errorf("Expected no uncalled stubs, got %#v", uncalled)
+	}
+
+	if status := adminRequest(t, client, "POST", admin+"reset", "", nil); status != http.StatusNoContent {
+		t.Errorf("Unexpected status resetting: %d", status)
+	}
+
+	if len(transport.StubRequests()) != 0 || len(transport.Journal()) != 0 {
+		t.Errorf("Expected transport to be reset")
+	}
+
+	if status := adminRequest(t, client, "GET", admin+"unknown", "", nil); status != http.StatusNotFound {
+		t.Errorf("Unexpected status for unknown endpoint: %d", status)
+	}
+}
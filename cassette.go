@@ -0,0 +1,186 @@
+package simular
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cassette holds a list of recorded request/response interactions. A
+// MockTransport can record real interactions onto a cassette which can be
+// saved to disk, and then later loaded and replayed as stubbed requests.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+
+	// IgnoreHeaders lists request headers which are neither saved to disk nor
+	// required to be present when replaying, such as credentials or headers
+	// which change with every request (e.g. request IDs). NewCassette ignores
+	// DefaultIgnoredHeaders.
+	IgnoreHeaders []string `json:"-"`
+
+	mu sync.Mutex
+}
+
+// DefaultIgnoredHeaders are the request headers ignored by cassettes created
+// with NewCassette or LoadCassette, as they hold credentials which shouldn't
+// be written to disk.
+var DefaultIgnoredHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+// Interaction is a single recorded request and the response it received
+type Interaction struct {
+	Request  *RecordedRequest  `json:"request"`
+	Response *RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the serialized form of a recorded request. The body is
+// stored as raw bytes, which are base64 encoded when saved, so binary bodies
+// survive a round trip to disk.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the serialized form of a recorded response. As with
+// RecordedRequest, the body is base64 encoded when saved.
+type RecordedResponse struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+}
+
+// NewCassette returns a new empty Cassette
+func NewCassette() *Cassette {
+	return &Cassette{
+		Interactions:  make([]*Interaction, 0),
+		IgnoreHeaders: append([]string(nil), DefaultIgnoredHeaders...),
+	}
+}
+
+// LoadCassette reads a Cassette previously written by Save from the given path
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewCassette()
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Save writes the cassette to the given path as JSON, creating any missing
+// parent directories. Headers listed in IgnoreHeaders are left out.
+func (c *Cassette) Save(path string) error {
+	c.mu.Lock()
+	saved := &Cassette{Interactions: make([]*Interaction, 0, len(c.Interactions))}
+	for _, interaction := range c.Interactions {
+		req := *interaction.Request
+		req.Header = c.requestHeader(req.Header)
+
+		saved.Interactions = append(saved.Interactions, &Interaction{Request: &req, Response: interaction.Response})
+	}
+	c.mu.Unlock()
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// StubRequests returns a StubRequest for every interaction on the cassette,
+// suitable for passing to RegisterStubRequests. Each stub requires the
+// recorded headers, other than those in IgnoreHeaders, and body to be present
+// on a request in order to match, and responds with the recorded response
+// once, so a request recorded several times (e.g. polling for a status) is
+// answered with each recorded response in turn.
+func (c *Cassette) StubRequests() []*StubRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stubs := make([]*StubRequest, 0, len(c.Interactions))
+
+	for _, interaction := range c.Interactions {
+		req, recorded := interaction.Request, interaction.Response
+
+		resp := NewBytesResponse(recorded.StatusCode, recorded.Body)
+		resp.Header = cloneHeader(recorded.Header)
+		if resp.Header == nil {
+			resp.Header = http.Header{}
+		}
+
+		options := []Option{Once()}
+
+		if header := c.requestHeader(req.Header); len(header) > 0 {
+			options = append(options, WithHeader(&header))
+		}
+
+		if len(req.Body) > 0 {
+			options = append(options, WithBody(bytes.NewReader(req.Body)))
+		}
+
+		stubs = append(stubs, NewStubRequest(req.Method, req.URL, ResponderFromResponse(resp), options...))
+	}
+
+	return stubs
+}
+
+// requestHeader returns a copy of the recorded request header without the
+// headers listed in IgnoreHeaders. The caller must hold c.mu.
+func (c *Cassette) requestHeader(header http.Header) http.Header {
+	filtered := cloneHeader(header)
+	for _, name := range c.IgnoreHeaders {
+		filtered.Del(name)
+	}
+	return filtered
+}
+
+// record forwards the given request to the transport, adding the request and
+// the response received to the cassette.
+func (c *Cassette) record(transport http.RoundTripper, req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Interactions = append(c.Interactions, &Interaction{
+		Request: &RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: cloneHeader(req.Header),
+			Body:   body,
+		},
+		Response: &RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     cloneHeader(resp.Header),
+			Body:       respBody,
+		},
+	})
+
+	return resp, nil
+}
@@ -0,0 +1,248 @@
+package simular
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+// echoTransport is a round tripper that responds with the body of the request
+type echoTransport struct{}
+
+func (e *echoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := NewBytesResponse(200, body)
+	resp.Header.Set("X-Echo", "true")
+
+	return resp, nil
+}
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures", "cassette.json")
+	cassette := NewCassette()
+
+	transport, client := NewMockClient(
+		WithPassthrough(&echoTransport{}),
+		WithRecorder(cassette),
+	)
+
+	req, err := http.NewRequest("POST", testURL, bytes.NewBufferString("hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-ApiKey", "api-key")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error recording request: %v", err)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "hello world" {
+		t.Errorf("Unexpected body from recorded request: %s", body)
+	}
+
+	if len(transport.Journal()) != 1 {
+		t.Errorf("Expected recorded request to be in the journal")
+	}
+
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("Unexpected error saving cassette: %v", err)
+	}
+
+	loaded, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("Unexpected error loading cassette: %v", err)
+	}
+
+	if len(loaded.Interactions) != 1 {
+		t.Fatalf("Expected 1 interaction, got %d", len(loaded.Interactions))
+	}
+
+	replay, replayClient := NewMockClient()
+	replay.RegisterStubRequests(loaded.StubRequests()...)
+
+	// without the recorded header the request should not match
+	_, err = replayClient.Post(testURL, "text/plain", bytes.NewBufferString("hello world"))
+	if err == nil {
+		t.Error("Expected error replaying request without recorded header")
+	}
+
+	req, err = http.NewRequest("POST", testURL, bytes.NewBufferString("hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-ApiKey", "api-key")
+
+	resp, err = replayClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error replaying request: %v", err)
+	}
+
+	body, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "hello world" || resp.Header.Get("X-Echo") != "true" {
+		t.Errorf("Unexpected replayed response: %s %v", body, resp.Header)
+	}
+
+	if err := replay.AllStubsCalled(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestLoadCassetteMissing(t *testing.T) {
+	_, err := LoadCassette(filepath.Join(t.TempDir(), "missing.json"))
+	if err == nil {
+		t.Error("Expected error loading missing cassette")
+	}
+}
+
+// pollingTransport is a round tripper that responds with each of its
+// responses in turn
+type pollingTransport struct {
+	bodies [][]byte
+}
+
+func (p *pollingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := p.bodies[0]
+	p.bodies = p.bodies[1:]
+
+	return NewBytesResponse(200, body), nil
+}
+
+func TestCassetteReplayInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cassette := NewCassette()
+
+	// a binary body that isn't valid UTF-8
+	binary := []byte{0x1f, 0x8b, 0x08, 0xff, 0xfe, 0x00}
+
+	_, client := NewMockClient(
+		WithPassthrough(&pollingTransport{bodies: [][]byte{[]byte("pending"), []byte("pending"), binary}}),
+		WithRecorder(cassette),
+	)
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(testURL)
+		if err != nil {
+			t.Fatalf("Unexpected error recording request: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("Unexpected error saving cassette: %v", err)
+	}
+
+	loaded, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("Unexpected error loading cassette: %v", err)
+	}
+
+	replay, replayClient := NewMockClient()
+	replay.RegisterStubRequests(loaded.StubRequests()...)
+
+	for _, expected := range [][]byte{[]byte("pending"), []byte("pending"), binary} {
+		resp, err := replayClient.Get(testURL)
+		if err != nil {
+			t.Fatalf("Unexpected error replaying request: %v", err)
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(body, expected) {
+			t.Errorf("Unexpected replayed body, expected %v, got %v", expected, body)
+		}
+	}
+
+	if err := replay.AllStubsCalled(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// every recorded interaction has now been used up
+	if _, err := replayClient.Get(testURL); err == nil {
+		t.Error("Expected error once all recorded interactions were replayed")
+	}
+}
+
+func TestCassetteIgnoreHeaders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cassette := NewCassette()
+	cassette.IgnoreHeaders = append(cassette.IgnoreHeaders, "X-Request-Id")
+
+	_, client := NewMockClient(
+		WithPassthrough(&echoTransport{}),
+		WithRecorder(cassette),
+	)
+
+	req, err := http.NewRequest("POST", testURL, bytes.NewBufferString("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("X-Request-Id", "1")
+	req.Header.Set("X-Tenant", "acme")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error recording request: %v", err)
+	}
+	resp.Body.Close()
+
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("Unexpected error saving cassette: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(data, []byte("secret-token")) || bytes.Contains(data, []byte("X-Request-Id")) {
+		t.Errorf("Expected ignored headers to be left out of saved cassette, got %s", data)
+	}
+
+	loaded, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("Unexpected error loading cassette: %v", err)
+	}
+
+	// ignored headers are also not required by stubs built from a cassette in
+	// memory
+	for _, c := range []*Cassette{loaded, cassette} {
+		replay, replayClient := NewMockClient()
+		replay.RegisterStubRequests(c.StubRequests()...)
+
+		req, err := http.NewRequest("POST", testURL, bytes.NewBufferString("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Request-Id", "2")
+		req.Header.Set("X-Tenant", "acme")
+
+		resp, err := replayClient.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error replaying request: %v", err)
+		}
+		resp.Body.Close()
+	}
+}
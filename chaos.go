@@ -0,0 +1,173 @@
+package simular
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultChaosStatuses are the statuses used for injected errors when a
+// Chaos configuration doesn't list any.
+var defaultChaosStatuses = []int{
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Chaos configures faults to be injected at random into otherwise successful
+// stubbed responses, for testing the resilience of a client. Each rate is a
+// probability between 0 and 1. The same Seed always produces the same
+// sequence of faults for the same sequence of requests.
+type Chaos struct {
+	// Seed seeds the random number generator deciding which faults to inject
+	Seed int64
+
+	// DropRate is the probability of a request failing as though its
+	// connection was reset, without any response
+	DropRate float64
+
+	// ErrorRate is the probability of a response being replaced by an empty
+	// response with a status chosen from ErrorStatuses
+	ErrorRate float64
+
+	// ErrorStatuses are the statuses used for injected errors, defaulting to
+	// 500, 502, 503 and 504
+	ErrorStatuses []int
+
+	// LatencyRate is the probability of a response being delayed by a
+	// duration between MinLatency and MaxLatency
+	LatencyRate float64
+	MinLatency  time.Duration
+	MaxLatency  time.Duration
+
+	// TruncateRate is the probability of a response body being cut short,
+	// failing with io.ErrUnexpectedEOF part way through
+	TruncateRate float64
+}
+
+// WithChaos is a TransportOption that enables chaos mode, injecting faults
+// into stubbed responses as configured by the given Chaos.
+func WithChaos(chaos Chaos) TransportOption {
+	return func(m *MockTransport) {
+		m.chaos = newChaosMonkey(chaos)
+	}
+}
+
+// SetChaos enables chaos mode on the transport, injecting faults into stubbed
+// responses as configured by the given Chaos. Passing nil disables chaos
+// mode.
+func (m *MockTransport) SetChaos(chaos *Chaos) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if chaos == nil {
+		m.chaos = nil
+		return
+	}
+	m.chaos = newChaosMonkey(*chaos)
+}
+
+// SetChaos enables chaos mode on the default mock transport. Passing nil
+// disables chaos mode.
+func SetChaos(chaos *Chaos) {
+	mockTransport.SetChaos(chaos)
+}
+
+// chaosMonkey injects faults into responses as configured by a Chaos
+type chaosMonkey struct {
+	config Chaos
+	rng    *rand.Rand
+	mu     sync.Mutex
+}
+
+// chaosFaults records which faults were chosen for a single response
+type chaosFaults struct {
+	drop     bool
+	status   int
+	latency  time.Duration
+	truncate float64
+}
+
+// newChaosMonkey returns a chaosMonkey for the given configuration
+func newChaosMonkey(config Chaos) *chaosMonkey {
+	if len(config.ErrorStatuses) == 0 {
+		config.ErrorStatuses = defaultChaosStatuses
+	}
+
+	return &chaosMonkey{
+		config: config,
+		rng:    rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// choose decides which faults to inject into the next response. The same
+// number of random values is drawn for every response, so that the faults
+// chosen for a request don't depend on those chosen for earlier requests.
+func (c *chaosMonkey) choose() *chaosFaults {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	drop, errorRoll, statusRoll := c.rng.Float64(), c.rng.Float64(), c.rng.Intn(len(c.config.ErrorStatuses))
+	latencyRoll, latencyFraction := c.rng.Float64(), c.rng.Float64()
+	truncateRoll, truncateFraction := c.rng.Float64(), c.rng.Float64()
+
+	faults := &chaosFaults{
+		drop:     drop < c.config.DropRate,
+		truncate: -1,
+	}
+
+	if errorRoll < c.config.ErrorRate {
+		faults.status = c.config.ErrorStatuses[statusRoll]
+	}
+
+	if latencyRoll < c.config.LatencyRate {
+		faults.latency = c.config.MinLatency + time.Duration(latencyFraction*float64(c.config.MaxLatency-c.config.MinLatency))
+	}
+
+	if truncateRoll < c.config.TruncateRate {
+		faults.truncate = truncateFraction
+	}
+
+	return faults
+}
+
+// apply injects any chosen faults into the response
+func (c *chaosMonkey) apply(req *http.Request, resp *http.Response) (*http.Response, error) {
+	faults := c.choose()
+
+	if err := wait(req, faults.latency); err != nil {
+		return nil, err
+	}
+
+	if faults.drop {
+		return nil, resetError(req)
+	}
+
+	if faults.status != 0 {
+		if resp.Body != nil {
+			resp.Body.Close()
+		}
+		return NewStringResponse(faults.status, ""), nil
+	}
+
+	if faults.truncate >= 0 && resp.Body != nil {
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Body = &failingBody{
+			body:      ioutil.NopCloser(bytes.NewReader(body)),
+			remaining: int64(faults.truncate * float64(len(body))),
+			err:       io.ErrUnexpectedEOF,
+		}
+	}
+
+	return resp, nil
+}
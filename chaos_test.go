@@ -0,0 +1,110 @@
+package simular
+
+import (
+	"io"
+	"io/ioutil"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// chaosOutcomes makes a series of requests with the given chaos configuration
+// returning a description of the outcome of each one.
+func chaosOutcomes(t *testing.T, chaos Chaos, n int) []string {
+	t.Helper()
+
+	transport, client := NewMockClient(WithChaos(chaos))
+	transport.RegisterStubRequests(NewStubRequest("GET", testURL, NewStringResponder(200, "hello world")))
+
+	outcomes := []string{}
+
+	for i := 0; i < n; i++ {
+		resp, err := client.Get(testURL)
+		if err != nil {
+			outcomes = append(outcomes, "dropped")
+			continue
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		switch {
+		case err == io.ErrUnexpectedEOF:
+			outcomes = append(outcomes, "truncated")
+		case err != nil:
+			t.Fatalf("Unexpected error reading body: %v", err)
+		case resp.StatusCode != 200:
+			outcomes = append(outcomes, strconv.Itoa(resp.StatusCode))
+		case string(body) != "hello world":
+			t.Fatalf("Unexpected body: %s", body)
+		default:
+			outcomes = append(outcomes, "ok")
+		}
+	}
+
+	return outcomes
+}
+
+func TestChaosReproducible(t *testing.T) {
+	chaos := Chaos{
+		Seed:         42,
+		DropRate:     0.2,
+		ErrorRate:    0.2,
+		TruncateRate: 0.2,
+	}
+
+	first := chaosOutcomes(t, chaos, 50)
+	second := chaosOutcomes(t, chaos, 50)
+
+	seen := map[string]bool{}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected the same outcomes for the same seed, differed at request %d: %s != %s", i, first[i], second[i])
+		}
+		seen[first[i]] = true
+	}
+
+	for _, outcome := range []string{"ok", "dropped", "truncated"} {
+		if !seen[outcome] {
+			t.Errorf("Expected some requests to be %s, got %v", outcome, first)
+		}
+	}
+}
+
+func TestChaosRates(t *testing.T) {
+	for _, outcome := range chaosOutcomes(t, Chaos{Seed: 1}, 20) {
+		if outcome != "ok" {
+			t.Fatalf("Expected no faults with zero rates, got %s", outcome)
+		}
+	}
+
+	for _, outcome := range chaosOutcomes(t, Chaos{Seed: 1, ErrorRate: 1, ErrorStatuses: []int{503}}, 20) {
+		if outcome != "503" {
+			t.Fatalf("Expected every response to be a 503, got %s", outcome)
+		}
+	}
+}
+
+func TestChaosLatency(t *testing.T) {
+	transport, client := NewMockClient()
+	transport.RegisterStubRequests(NewStubRequest("GET", testURL, NewStringResponder(200, "ok")))
+	transport.SetChaos(&Chaos{LatencyRate: 1, MinLatency: 10 * time.Millisecond, MaxLatency: 20 * time.Millisecond})
+
+	start := time.Now()
+
+	resp, err := client.Get(testURL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if time.Since(start) < 10*time.Millisecond {
+		t.Errorf("Expected latency to be added")
+	}
+
+	transport.SetChaos(nil)
+
+	if transport.chaos != nil {
+		t.Errorf("Expected chaos mode to be disabled")
+	}
+}
@@ -0,0 +1,211 @@
+// Command simular runs a standalone mock HTTP server serving the stub
+// definitions found in a directory, allowing non Go clients to be developed
+// and tested against the same fixtures as Go tests using simular. The
+// definitions are reloaded whenever the files change, and when the server is
+// reset via the admin API.
+//
+// Usage:
+// 		simular -dir ./fixtures -addr localhost:8080 -upstream https://api.example.com
+//
+// See simular.LoadStubs for the format of the stub definition files, and
+// simular.MockTransport.Handler for how requests are matched against stubs.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thingful/simular"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	dir := flag.String("dir", ".", "file or directory containing stub definitions")
+	upstream := flag.String("upstream", "", "base URL requests are matched against, defaults to using the Host header")
+	interval := flag.Duration("interval", time.Second, "interval at which to check stub definitions for changes")
+	flag.Parse()
+
+	transport := simular.NewMockTransport()
+
+	r := &reloader{
+		path:      *dir,
+		transport: transport,
+	}
+
+	if err := r.reload(); err != nil {
+		log.Fatalf("unable to load stub definitions: %s", err)
+	}
+
+	go r.watch(*interval)
+
+	log.Printf("serving stubs from %s on %s", *dir, *addr)
+	log.Fatal(http.ListenAndServe(*addr, r.handleReset(logUnmatched(transport.Handler(*upstream)))))
+}
+
+// reloader loads stub definitions into a transport, reloading them whenever
+// the files change.
+type reloader struct {
+	path        string
+	transport   *simular.MockTransport
+	fingerprint string
+
+	// stubs are those last loaded from the files
+	stubs []*simular.StubRequest
+
+	// mu guards fingerprint and stubs, as the transport may be reset while
+	// the files are being watched
+	mu sync.Mutex
+}
+
+// reload loads the stub definitions if they have changed since they were
+// last loaded, replacing the stubs previously loaded from the files. Stubs
+// added via the admin API, the journal and scenario state are kept.
+func (r *reloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fingerprint, err := fingerprint(r.path)
+	if err != nil {
+		return err
+	}
+
+	if fingerprint == r.fingerprint {
+		return nil
+	}
+
+	stubs, err := simular.LoadStubs(r.path)
+	if err != nil {
+		return err
+	}
+
+	r.transport.ReplaceStubRequests(r.stubs, stubs...)
+	r.stubs = stubs
+	r.fingerprint = fingerprint
+
+	log.Printf("loaded %d stubs from %s", len(stubs), r.path)
+
+	return nil
+}
+
+// reset resets the transport as the admin API does, and then registers fresh
+// copies of the stubs defined in the files, so they are still served and their
+// calls are counted from zero. If the files can't be loaded the transport is
+// left untouched.
+func (r *reloader) reset() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fingerprint, err := fingerprint(r.path)
+	if err != nil {
+		return err
+	}
+
+	stubs, err := simular.LoadStubs(r.path)
+	if err != nil {
+		return err
+	}
+
+	r.transport.Reset()
+	r.transport.RegisterStubRequests(stubs...)
+	r.stubs = stubs
+	r.fingerprint = fingerprint
+
+	return nil
+}
+
+// handleReset wraps the handler, serving requests to reset the transport via
+// the admin API with reset, rather than leaving the transport without the
+// stubs defined in the files.
+func (r *reloader) handleReset(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || strings.TrimSuffix(req.URL.Path, "/") != simular.AdminPrefix+"reset" {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		if err := r.reset(); err != nil {
+			http.Error(w, fmt.Sprintf("unable to reload stub definitions: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// watch periodically reloads the stub definitions. Errors are logged, leaving
+// the previously loaded stubs in place.
+func (r *reloader) watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := r.reload(); err != nil {
+			log.Printf("unable to reload stub definitions: %s", err)
+		}
+	}
+}
+
+// fingerprint returns a string identifying the current state of every file
+// beneath the given path, which changes whenever any file is added, removed
+// or modified.
+func fingerprint(path string) (string, error) {
+	entries := []string{}
+
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			entries = append(entries, fmt.Sprintf("%s:%d:%d", p, info.Size(), info.ModTime().UnixNano()))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	sort.Strings(entries)
+
+	return strings.Join(entries, "\n"), nil
+}
+
+// logUnmatched wraps the handler, logging the diagnostics for any request
+// that matched no stub.
+func logUnmatched(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &unmatchedRecorder{ResponseWriter: w}
+		handler.ServeHTTP(rec, r)
+
+		if rec.unmatched {
+			log.Printf("unmatched request %s %s: %s", r.Method, r.URL, strings.TrimSpace(rec.body.String()))
+		}
+	})
+}
+
+// unmatchedRecorder is an http.ResponseWriter capturing the body of responses
+// flagged as being for unmatched requests.
+type unmatchedRecorder struct {
+	http.ResponseWriter
+	unmatched bool
+	body      bytes.Buffer
+}
+
+func (u *unmatchedRecorder) WriteHeader(status int) {
+	u.unmatched = u.Header().Get(simular.UnmatchedHeader) != ""
+	u.ResponseWriter.WriteHeader(status)
+}
+
+func (u *unmatchedRecorder) Write(p []byte) (int, error) {
+	if u.unmatched {
+		u.body.Write(p)
+	}
+	return u.ResponseWriter.Write(p)
+}
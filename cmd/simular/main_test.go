@@ -0,0 +1,153 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thingful/simular"
+)
+
+func writeStubs(t *testing.T, path, body string) {
+	t.Helper()
+
+	contents := "- method: GET\n  url: http://api.example.com/status\n  response:\n    body: " + body + "\n"
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stubs.yaml")
+	writeStubs(t, path, "first")
+
+	transport := simular.NewMockTransport()
+	r := &reloader{path: dir, transport: transport}
+
+	if err := r.reload(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	server := httptest.NewServer(logUnmatched(transport.Handler("http://api.example.com")))
+	defer server.Close()
+
+	if status, body := get(t, server.URL+"/status"); status != 200 || body != "first" {
+		t.Errorf("Unexpected response: %d %s", status, body)
+	}
+
+	// stubs added other than from the files survive reloads
+	transport.RegisterStubRequests(simular.NewStubRequest("GET", "http://api.example.com/extra", simular.NewStringResponder(200, "extra")))
+
+	writeStubs(t, path, "second")
+
+	// make sure the modification time changes even on coarse filesystems
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.reload(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if status, body := get(t, server.URL+"/status"); status != 200 || body != "second" {
+		t.Errorf("Unexpected response after reload: %d %s", status, body)
+	}
+
+	if status, body := get(t, server.URL+"/extra"); status != 200 || body != "extra" {
+		t.Errorf("Unexpected response for added stub after reload: %d %s", status, body)
+	}
+
+	if len(transport.Journal()) != 3 {
+		t.Errorf("Expected journal to be kept after reload, got %d entries", len(transport.Journal()))
+	}
+
+	if status, _ := get(t, server.URL+"/unknown"); status != 404 {
+		t.Errorf("Unexpected status for unmatched request: %d", status)
+	}
+
+	// invalid definitions leave the existing stubs in place
+	if err := ioutil.WriteFile(path, []byte("- method: [GET"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.reload(); err == nil {
+		t.Errorf("Expected error reloading invalid definitions")
+	}
+
+	if status, body := get(t, server.URL+"/status"); status != 200 || body != "second" {
+		t.Errorf("Unexpected response after failed reload: %d %s", status, body)
+	}
+}
+
+func TestReloaderReset(t *testing.T) {
+	dir := t.TempDir()
+	writeStubs(t, filepath.Join(dir, "stubs.yaml"), "first")
+
+	transport := simular.NewMockTransport()
+	r := &reloader{path: dir, transport: transport}
+
+	if err := r.reload(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	server := httptest.NewServer(r.handleReset(logUnmatched(transport.Handler("http://api.example.com"))))
+	defer server.Close()
+
+	transport.RegisterStubRequests(simular.NewStubRequest("GET", "http://api.example.com/extra", simular.NewStringResponder(200, "extra")))
+
+	if status, body := get(t, server.URL+"/status"); status != 200 || body != "first" {
+		t.Errorf("Unexpected response: %d %s", status, body)
+	}
+
+	resp, err := http.Post(server.URL+simular.AdminPrefix+"reset", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Unexpected status for reset: %d", resp.StatusCode)
+	}
+
+	if len(transport.Journal()) != 0 {
+		t.Errorf("Expected journal to be cleared by reset, got %d entries", len(transport.Journal()))
+	}
+
+	// the stubs defined in the files are still served, with their calls
+	// counted afresh
+	if status, body := get(t, server.URL+"/status"); status != 200 || body != "first" {
+		t.Errorf("Unexpected response after reset: %d %s", status, body)
+	}
+
+	stubs := transport.StubRequests()
+	if len(stubs) != 1 || stubs[0].CallCount() != 1 {
+		t.Errorf("Expected only the stubs from the files after reset, got %v", stubs)
+	}
+
+	if status, _ := get(t, server.URL+"/extra"); status != 404 {
+		t.Errorf("Expected stubs added via the admin API to be removed by reset, got %d", status)
+	}
+}
@@ -0,0 +1,199 @@
+package simular
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// StubDefinition is the declarative form of a StubRequest, as read from YAML
+// or JSON files by LoadStubs. For example in YAML:
+// 		- method: GET
+// 		  url: https://api.mybiz.com/articles.json
+// 		  header:
+// 		    Api-Key: 1234abcd
+// 		  response:
+// 		    status: 200
+// 		    header:
+// 		      Content-Type: application/json
+// 		    body_file: articles.json
+type StubDefinition struct {
+	Method   string              `json:"method" yaml:"method"`
+	URL      string              `json:"url" yaml:"url"`
+	Header   map[string]string   `json:"header,omitempty" yaml:"header,omitempty"`
+	Body     string              `json:"body,omitempty" yaml:"body,omitempty"`
+	Response *ResponseDefinition `json:"response" yaml:"response"`
+}
+
+// ResponseDefinition is the declarative form of the response returned by a
+// stub. The body can be given either inline or as a path to a file, which if
+// relative is resolved against the directory of the definition file.
+type ResponseDefinition struct {
+	Status   int               `json:"status,omitempty" yaml:"status,omitempty"`
+	Header   map[string]string `json:"header,omitempty" yaml:"header,omitempty"`
+	Body     string            `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyFile string            `json:"body_file,omitempty" yaml:"body_file,omitempty"`
+}
+
+// StubRequest converts the definition into a StubRequest. Returns an error if
+// the definition is invalid, or if the response body file can't be read.
+func (d *StubDefinition) StubRequest() (*StubRequest, error) {
+	if d.Method == "" || d.URL == "" {
+		return nil, fmt.Errorf("Invalid stub definition, method and url are required")
+	}
+
+	response := d.Response
+	if response == nil {
+		response = &ResponseDefinition{}
+	}
+
+	status := response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	body := []byte(response.Body)
+	if response.BodyFile != "" {
+		var err error
+		body, err = ioutil.ReadFile(response.BodyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp := NewBytesResponse(status, body)
+	for k, v := range response.Header {
+		resp.Header.Set(k, v)
+	}
+
+	options := []Option{}
+
+	if len(d.Header) > 0 {
+		header := http.Header{}
+		for k, v := range d.Header {
+			header.Set(k, v)
+		}
+		options = append(options, WithHeader(&header))
+	}
+
+	if d.Body != "" {
+		options = append(options, WithBody(bytes.NewBufferString(d.Body)))
+	}
+
+	stub := NewStubRequest(d.Method, d.URL, ResponderFromResponse(resp), options...)
+	if err := stub.compileURL(); err != nil {
+		return nil, err
+	}
+
+	return stub, nil
+}
+
+// bodyFilesDir is the name of directories skipped when loading stub
+// definitions from a directory, allowing response body files to be kept
+// alongside the definitions.
+const bodyFilesDir = "__files"
+
+// LoadStubs reads stub definitions from the given path, returning the
+// corresponding stubbed requests ready to pass to RegisterStubRequests. The
+// path may be a single file, or a directory in which case every .json, .yaml
+// and .yml file beneath it is loaded in lexical order, except for those within
+// directories named __files which are reserved for response body files. Each
+// file should contain a list of stub definitions.
+func LoadStubs(path string) ([]*StubRequest, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return loadStubFile(path)
+	}
+
+	stubs := []*StubRequest{}
+
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == bodyFilesDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !isStubFile(p) {
+			return nil
+		}
+
+		fileStubs, err := loadStubFile(p)
+		if err != nil {
+			return err
+		}
+
+		stubs = append(stubs, fileStubs...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stubs, nil
+}
+
+// isStubFile returns true if the given path has an extension we know how to
+// load stub definitions from
+func isStubFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// loadStubFile parses the stub definitions contained in a single file
+func loadStubFile(path string) ([]*StubRequest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := []*StubDefinition{}
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(data, &definitions)
+	} else {
+		err = yaml.Unmarshal(data, &definitions)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse stub definitions in %s: %s", path, err)
+	}
+
+	stubs := make([]*StubRequest, 0, len(definitions))
+
+	for _, definition := range definitions {
+		if definition.Response != nil && definition.Response.BodyFile != "" && !filepath.IsAbs(definition.Response.BodyFile) {
+			definition.Response.BodyFile = filepath.Join(filepath.Dir(path), definition.Response.BodyFile)
+		}
+
+		stub, err := definition.StubRequest()
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load stub definitions in %s: %s", path, err)
+		}
+
+		stubs = append(stubs, stub)
+	}
+
+	return stubs, nil
+}
@@ -0,0 +1,169 @@
+package simular
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const yamlDefinitions = `
+- method: GET
+  url: http://example.com/articles.json
+  header:
+    Api-Key: 1234abcd
+  response:
+    status: 200
+    header:
+      Content-Type: application/json
+    body_file: __files/articles.json
+- method: POST
+  url: http://example.com/articles.json
+  body: '{"title":"article"}'
+  response:
+    status: 201
+    body: created
+`
+
+const jsonDefinitions = `[
+  {
+    "method": "DELETE",
+    "url": "http://example.com/articles/1.json",
+    "response": {
+      "status": 204
+    }
+  }
+]`
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadStubs(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "articles.yaml"), yamlDefinitions)
+	writeFile(t, filepath.Join(dir, "__files", "articles.json"), `[{"id":1}]`)
+	writeFile(t, filepath.Join(dir, "nested", "delete.json"), jsonDefinitions)
+	writeFile(t, filepath.Join(dir, "README.md"), "ignored")
+
+	stubs, err := LoadStubs(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(stubs) != 3 {
+		t.Fatalf("Expected 3 stubs, got %d", len(stubs))
+	}
+
+	transport, client := NewMockClient()
+	transport.RegisterStubRequests(stubs...)
+
+	req, err := http.NewRequest("GET", "http://example.com/articles.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Api-Key", "1234abcd")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != `[{"id":1}]` || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected response: %s %v", body, resp.Header)
+	}
+
+	resp, err = client.Post("http://example.com/articles.json", "application/json", bytes.NewBufferString(`{"title":"article"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 201 {
+		t.Errorf("Unexpected status, expected 201, got %d", resp.StatusCode)
+	}
+
+	req, err = http.NewRequest("DELETE", "http://example.com/articles/1.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 204 {
+		t.Errorf("Unexpected status, expected 204, got %d", resp.StatusCode)
+	}
+
+	if err := transport.AllStubsCalled(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestLoadStubsErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	testcases := []struct {
+		label    string
+		filename string
+		contents string
+	}{
+		{
+			"invalid yaml",
+			"invalid.yml",
+			"- method: [GET",
+		},
+		{
+			"missing url",
+			"missing.json",
+			`[{"method": "GET"}]`,
+		},
+		{
+			"invalid url pattern",
+			"pattern.json",
+			`[{"method": "GET", "url": "=~^http://example.com/("}]`,
+		},
+		{
+			"missing body file",
+			"body.yaml",
+			"- method: GET\n  url: http://example.com\n  response:\n    body_file: nonexistent.json\n",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.label, func(t *testing.T) {
+			path := filepath.Join(dir, testcase.filename)
+			writeFile(t, path, testcase.contents)
+
+			_, err := LoadStubs(path)
+			if err == nil {
+				t.Errorf("Expected error loading %s", testcase.filename)
+			}
+		})
+	}
+
+	_, err := LoadStubs(filepath.Join(dir, "missing"))
+	if err == nil {
+		t.Error("Expected error loading missing path")
+	}
+}
@@ -0,0 +1,308 @@
+package simular
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxCandidates is the number of closest matching stubs described in the
+// message of an ErrNoResponderFound error.
+const maxCandidates = 3
+
+// maxDiffValue is the length beyond which values are truncated when
+// describing a FieldDiff.
+const maxDiffValue = 200
+
+// FieldDiff describes a single requirement of a stub that a request did not
+// satisfy. Field names the requirement, e.g. "method", "url", "query page",
+// "header X-Apikey", "body", "matcher", "scenario" or "calls". Expected and
+// Actual hold the stub's and request's values where these are meaningful, and
+// Reason holds a description of the mismatch where they are not.
+type FieldDiff struct {
+	Field    string
+	Expected string
+	Actual   string
+	Reason   string
+}
+
+// String returns a human readable description of the difference
+func (d *FieldDiff) String() string {
+	if d.Reason != "" {
+		return fmt.Sprintf("%s: %s", d.Field, d.Reason)
+	}
+	return fmt.Sprintf("%s: expected %s, got %s", d.Field, truncate(d.Expected), truncate(d.Actual))
+}
+
+// Candidate is a registered stub considered for a request that matched no
+// stub, along with a score between 0 and 1 of how closely it matched and the
+// differences found.
+type Candidate struct {
+	Stub  *StubRequest
+	Score float64
+	Diffs []*FieldDiff
+}
+
+// String returns a human readable description of the candidate
+func (c *Candidate) String() string {
+	diffs := []string{}
+	for _, d := range c.Diffs {
+		diffs = append(diffs, d.String())
+	}
+
+	return fmt.Sprintf("%s (%.0f%% match): %s", c.Stub, c.Score*100, strings.Join(diffs, "; "))
+}
+
+// rejection records every reason a registered stub didn't match a request
+type rejection struct {
+	stub *StubRequest
+	errs []error
+}
+
+// rankCandidates describes how closely each rejected stub matched the
+// request, ordering the candidates from the closest match to the furthest.
+// This is only done once we know the error is returned to the client.
+func (e *ErrNoResponderFound) rankCandidates() {
+	candidates := make([]*Candidate, 0, len(e.rejections))
+	for _, r := range e.rejections {
+		candidates = append(candidates, r.candidate(e.url))
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	e.candidates = candidates
+}
+
+// candidate builds the Candidate for the rejected stub from the errors
+// collected while matching the request with the given URL. Each requirement
+// is weighted, with the URL and method counting for more than other
+// requirements when scoring. A mismatched URL still counts partly towards the
+// score according to how similar it is to the request URL, so the stubs
+// closest to the request are ranked first rather than in registration order.
+func (r *rejection) candidate(reqURL *url.URL) *Candidate {
+	candidate := &Candidate{Stub: r.stub}
+	total, missed := r.stub.requirementsWeight(), 0.0
+
+	for _, err := range r.errs {
+		weight := mismatchWeight(err)
+		if _, ok := err.(*ExhaustedError); ok {
+			total += weight
+		}
+
+		if mismatch, ok := err.(*MismatchError); ok && mismatch.Kind == MismatchURL && reqURL != nil {
+			weight *= 1 - urlSimilarity(r.stub.URL, reqURL)
+		}
+		missed += weight
+
+		candidate.Diffs = append(candidate.Diffs, diffFor(r.stub, err))
+	}
+
+	candidate.Score = 1
+	if total > 0 {
+		candidate.Score = math.Max(total-missed, 0) / total
+	}
+
+	return candidate
+}
+
+// requirementsWeight returns the total weight of the requirements a request
+// must satisfy to match the stub, excluding its expected calls.
+func (r *StubRequest) requirementsWeight() float64 {
+	total := 0.0
+
+	if r.Method != "" {
+		total += mismatchWeight(&MismatchError{Kind: MismatchMethod})
+	}
+
+	if r.URL != "" || len(r.queryMatchers) > 0 {
+		total += mismatchWeight(&MismatchError{Kind: MismatchURL})
+	}
+
+	if r.Header != nil {
+		total += float64(len(*r.Header))
+	}
+
+	if r.Body != nil || r.bodyErr != nil {
+		total++
+	}
+
+	if r.jsonBody != nil {
+		total++
+	}
+
+	total += float64(len(r.matchers))
+
+	if r.scenario != nil {
+		total++
+	}
+
+	return total
+}
+
+// mismatchWeight returns the weight of the requirement that failed with the
+// given error
+func mismatchWeight(err error) float64 {
+	if mismatch, ok := err.(*MismatchError); ok {
+		switch mismatch.Kind {
+		case MismatchMethod:
+			return 2
+		case MismatchURL, MismatchQuery:
+			return 3
+		}
+	}
+
+	return 1
+}
+
+// diffFor converts an error collected while matching the stub into a
+// FieldDiff
+func diffFor(stub *StubRequest, err error) *FieldDiff {
+	switch e := err.(type) {
+	case *MismatchError:
+		switch e.Kind {
+		case MismatchMethod:
+			return &FieldDiff{Field: "method", Expected: e.Expected, Actual: e.Actual}
+		case MismatchURL:
+			return &FieldDiff{Field: "url", Expected: stub.URL, Actual: e.Actual, Reason: e.Error()}
+		case MismatchQuery:
+			return &FieldDiff{Field: "query " + e.Name, Expected: e.Expected, Actual: e.Actual}
+		case MismatchHeader:
+			return &FieldDiff{Field: "header " + e.Name, Expected: e.Expected, Actual: e.Actual}
+		case MismatchBody:
+			if e.Err == nil && e.Expected != "" {
+				return &FieldDiff{Field: "body", Expected: e.Expected, Actual: e.Actual}
+			}
+			return &FieldDiff{Field: "body", Reason: e.Error()}
+		default:
+			return &FieldDiff{Field: "matcher", Reason: e.Error()}
+		}
+	case *ScenarioMismatchError:
+		return &FieldDiff{Field: "scenario " + e.Scenario, Expected: e.Expected, Actual: e.Actual}
+	case *ExhaustedError:
+		return &FieldDiff{Field: "calls", Reason: fmt.Sprintf("stub exhausted, already called %d times", e.Calls)}
+	default:
+		return &FieldDiff{Field: "request", Reason: err.Error()}
+	}
+}
+
+// maxURLSimilarity is the similarity of a mismatched stub URL whose host and
+// path segments all match the request, e.g. because only the query differs,
+// so that it never scores as highly as a matching URL.
+const maxURLSimilarity = 0.9
+
+// urlSimilarity grades how similar a stub URL which didn't match is to the
+// request URL, between 0 and maxURLSimilarity. A third of the similarity comes
+// from the scheme and host, and the rest from the number of leading path
+// segments matched. Segments of template and regular expression stub URLs
+// are matched as patterns.
+func urlSimilarity(stubURL string, reqURL *url.URL) float64 {
+	regex := strings.HasPrefix(stubURL, regexpURLPrefix)
+
+	raw := stubURL
+	if regex {
+		raw = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(raw, regexpURLPrefix), "^"), "$")
+	} else {
+		raw, _ = splitPatternQuery(raw)
+	}
+
+	scheme := ""
+	if i := strings.Index(raw, "://"); i >= 0 {
+		scheme, raw = raw[:i], raw[i+3:]
+	}
+
+	host, path := raw, ""
+	if i := strings.Index(raw, "/"); i >= 0 {
+		host, path = raw[:i], raw[i:]
+	}
+
+	if regex {
+		scheme, host = unescapeRegexp(scheme), unescapeRegexp(host)
+	}
+
+	hostScore := 0.0
+	if strings.EqualFold(host, reqURL.Host) && (scheme == "" || strings.EqualFold(scheme, reqURL.Scheme)) {
+		hostScore = 1
+	}
+
+	stubSegments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	reqSegments := strings.Split(strings.TrimPrefix(reqURL.EscapedPath(), "/"), "/")
+
+	shared := 0
+	for shared < len(stubSegments) && shared < len(reqSegments) {
+		if !segmentMatches(stubSegments[shared], reqSegments[shared], regex) {
+			break
+		}
+		shared++
+	}
+
+	segments := len(stubSegments)
+	if len(reqSegments) > segments {
+		segments = len(reqSegments)
+	}
+	pathScore := float64(shared) / float64(segments)
+
+	return maxURLSimilarity * (hostScore + 2*pathScore) / 3
+}
+
+// segmentMatches returns true if the path segment of a stub URL matches the
+// segment of the request URL, treating the stub segment as a regular
+// expression or template as appropriate.
+func segmentMatches(stubSegment, reqSegment string, regex bool) bool {
+	if stubSegment == reqSegment {
+		return true
+	}
+
+	var expr string
+	switch {
+	case regex:
+		expr = stubSegment
+	case templateToken.MatchString(stubSegment):
+		literals := templateToken.Split(stubSegment, -1)
+		tokens := templateToken.FindAllString(stubSegment, -1)
+
+		expr = regexp.QuoteMeta(literals[0])
+		for i, token := range tokens {
+			if token == "**" {
+				expr += ".*"
+			} else {
+				expr += "[^/]*"
+			}
+			expr += regexp.QuoteMeta(literals[i+1])
+		}
+	default:
+		return false
+	}
+
+	pattern, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return unescapeRegexp(stubSegment) == reqSegment
+	}
+
+	return pattern.MatchString(reqSegment)
+}
+
+// unescapeRegexp removes the escaping from literal characters in part of a
+// regular expression, e.g. api\.example\.com becomes api.example.com
+func unescapeRegexp(expr string) string {
+	var unescaped strings.Builder
+	for i := 0; i < len(expr); i++ {
+		if expr[i] == '\\' && i+1 < len(expr) {
+			i++
+		}
+		unescaped.WriteByte(expr[i])
+	}
+	return unescaped.String()
+}
+
+// truncate shortens long values for display
+func truncate(value string) string {
+	if len(value) <= maxDiffValue {
+		return value
+	}
+	return value[:maxDiffValue] + "..."
+}
@@ -0,0 +1,215 @@
+package simular
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestClosestMatchDiagnostics(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest(
+			"POST",
+			"http://example.com/users",
+			NewStringResponder(201, "created"),
+			WithHeader(&http.Header{"X-Api-Key": []string{"secret"}}),
+			WithBody(bytes.NewBufferString("name=bob")),
+		),
+		NewStubRequest(
+			"GET",
+			"http://example.com/users",
+			NewStringResponder(200, "users"),
+		),
+		NewStubRequest(
+			"DELETE",
+			"http://example.com/accounts",
+			NewStringResponder(204, ""),
+		),
+	)
+
+	req, _ := http.NewRequest("POST", "http://example.com/users", bytes.NewBufferString("name=alice"))
+	req.Header.Set("X-Api-Key", "wrong")
+
+	_, err := client.Do(req)
+	if err == nil {
+		t.Fatal("Expected error for unmatched request")
+	}
+
+	var noMatch *ErrNoResponderFound
+	if !errors.As(err, &noMatch) {
+		t.Fatalf("Expected ErrNoResponderFound, got %T", err)
+	}
+
+	candidates := noMatch.Candidates()
+	if len(candidates) != 3 {
+		t.Fatalf("Expected 3 candidates, got %d", len(candidates))
+	}
+
+	closest := candidates[0]
+	if closest.Stub.Method != "POST" {
+		t.Errorf("Expected POST stub to be closest, got %s", closest.Stub)
+	}
+
+	if len(closest.Diffs) != 2 {
+		t.Fatalf("Expected 2 diffs, got %v", closest.Diffs)
+	}
+
+	if closest.Diffs[0].Field != "header X-Api-Key" || closest.Diffs[0].Expected != "secret" || closest.Diffs[0].Actual != "wrong" {
+		t.Errorf("Unexpected header diff: %+v", closest.Diffs[0])
+	}
+
+	if closest.Diffs[1].Field != "body" || closest.Diffs[1].Expected != "name=bob" || closest.Diffs[1].Actual != "name=alice" {
+		t.Errorf("Unexpected body diff: %+v", closest.Diffs[1])
+	}
+
+	if candidates[2].Stub.Method != "DELETE" {
+		t.Errorf("Expected DELETE stub to be furthest, got %s", candidates[2].Stub)
+	}
+
+	for i := 1; i < len(candidates); i++ {
+		if candidates[i].Score > candidates[i-1].Score {
+			t.Errorf("Candidates not ordered by score: %v", candidates)
+		}
+	}
+
+	msg := err.Error()
+	for _, expected := range []string{
+		"No stub matched POST http://example.com/users",
+		"header X-Api-Key: expected secret, got wrong",
+		"body: expected name=bob, got name=alice",
+		"method: expected GET, got POST",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error to contain %q, got %s", expected, msg)
+		}
+	}
+}
+
+func TestClosestMatchDiagnosticsLimitsCandidates(t *testing.T) {
+	transport, client := NewMockClient()
+
+	for _, path := range []string{"/a", "/b", "/c", "/d", "/e"} {
+		transport.RegisterStubRequests(
+			NewStubRequest("GET", "http://example.com"+path, NewStringResponder(200, "ok")),
+		)
+	}
+
+	_, err := client.Get("http://example.com/z")
+	if err == nil {
+		t.Fatal("Expected error for unmatched request")
+	}
+
+	var noMatch *ErrNoResponderFound
+	errors.As(err, &noMatch)
+	if len(noMatch.Candidates()) != 5 {
+		t.Errorf("Expected all stubs as candidates, got %d", len(noMatch.Candidates()))
+	}
+
+	if lines := strings.Count(noMatch.Error(), "\n"); lines != maxCandidates {
+		t.Errorf("Expected %d candidates in message, got %d: %s", maxCandidates, lines, noMatch.Error())
+	}
+}
+
+func TestClosestMatchDiagnosticsNoStubs(t *testing.T) {
+	_, client := NewMockClient()
+
+	_, err := client.Get("http://example.com/")
+	if err == nil {
+		t.Fatal("Expected error for unmatched request")
+	}
+
+	if !strings.Contains(err.Error(), "No responders found") {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestClosestMatchDiagnosticsOnlyWhenReturned(t *testing.T) {
+	calls := 0
+	matcher := MatcherFunc(func(req *http.Request) error {
+		calls++
+		return errors.New("never matches")
+	})
+
+	transport, client := NewMockClient(WithPassthrough(&pollingTransport{bodies: [][]byte{[]byte("real")}}), AllowHosts("example.com"))
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", "http://example.com/users", NewStringResponder(200, "users"), WithMatcher(matcher)),
+	)
+
+	resp, err := client.Get("http://example.com/users")
+	if err != nil {
+		t.Fatalf("Unexpected error for passthrough request: %v", err)
+	}
+	resp.Body.Close()
+
+	if calls != 1 {
+		t.Errorf("Expected matcher to be called once, got %d", calls)
+	}
+
+	// without passthrough the error describes the closest stubs, built from
+	// the same matching pass
+	other, client := NewMockClient()
+	other.RegisterStubRequests(
+		NewStubRequest("GET", "http://example.com/users", NewStringResponder(200, "users"), WithMatcher(matcher)),
+	)
+
+	_, err = client.Get("http://example.com/users")
+	if err == nil {
+		t.Fatal("Expected error for unmatched request")
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected matcher to be called once more, got %d", calls)
+	}
+
+	if !strings.Contains(err.Error(), "matcher: never matches") {
+		t.Errorf("Expected error to describe the matcher, got %s", err)
+	}
+}
+
+func TestClosestMatchDiagnosticsRanksSimilarURLs(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", "http://example.com/a", NewStringResponder(200, "a")),
+		NewStubRequest("GET", "http://other.com/users/1/orders", NewStringResponder(200, "other")),
+		NewStubRequest("GET", "http://example.com/b/c", NewStringResponder(200, "b")),
+		NewStubRequest("GET", "http://example.com/users/{id}", NewStringResponder(200, "user")),
+		NewStubRequest("GET", `=~^http://example\.com/users/(?P<id>\d+)/orders$`, NewStringResponder(200, "orders")),
+	)
+
+	_, err := client.Get("http://example.com/users/12/orders/")
+	if err == nil {
+		t.Fatal("Expected error for unmatched request")
+	}
+
+	var noMatch *ErrNoResponderFound
+	if !errors.As(err, &noMatch) {
+		t.Fatalf("Expected ErrNoResponderFound, got %T", err)
+	}
+
+	// the stubs sharing the most of the path are closest, even though they
+	// were registered last, and stubs for another host are furthest
+	candidates := noMatch.Candidates()
+	expected := []string{
+		`=~^http://example\.com/users/(?P<id>\d+)/orders$`,
+		"http://example.com/users/{id}",
+	}
+
+	for i, url := range expected {
+		if candidates[i].Stub.URL != url {
+			t.Errorf("Expected candidate %d to be %s, got %s", i, url, candidates[i])
+		}
+	}
+
+	if candidates[1].Score <= candidates[2].Score {
+		t.Errorf("Expected closer URLs to score higher, got %v", candidates)
+	}
+
+	if furthest := candidates[len(candidates)-1]; furthest.Stub.URL != "http://other.com/users/1/orders" {
+		t.Errorf("Expected stub for another host to be furthest, got %s", furthest)
+	}
+}
@@ -90,5 +90,41 @@ Advanced Example:
 		}
 	}
 
+Scenario Example:
+	func TestCreateArticle(t *testing.T) {
+		simular.ActivateT(t)
+
+		// rather than sharing state between responders, stubs can be part of a
+		// named scenario, only matching while it is in a given state and moving
+		// it to a new state when they respond
+		simular.RegisterStubRequests(
+			simular.NewStubRequest(
+				"GET",
+				"https://api.mybiz.com/articles.json",
+				simular.NewStringResponder(200, `[]`),
+				simular.WithScenario("articles", simular.ScenarioStarted, ""),
+			),
+			simular.NewStubRequest(
+				"POST",
+				"https://api.mybiz.com/articles.json",
+				simular.NewStringResponder(201, `{"title":"article"}`),
+				simular.WithScenario("articles", simular.ScenarioStarted, "created"),
+				simular.WithJSONBody(`{"title":"article"}`),
+			),
+			simular.NewStubRequest(
+				"GET",
+				"https://api.mybiz.com/articles.json",
+				simular.NewStringResponder(200, `[{"title":"article"}]`),
+				simular.WithScenario("articles", "created", ""),
+			),
+		)
+
+		// do stuff that lists, adds and then lists articles again
+
+		if state := simular.ScenarioState("articles"); state != "created" {
+			t.Errorf("Expected an article to have been created, got state %s", state)
+		}
+	}
+
 */
 package simular
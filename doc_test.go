@@ -65,7 +65,7 @@ func ExampleRegisterStubRequests() {
 	// also ok
 }
 
-func ExampleRegisterStubRequest_WithHeader() {
+func ExampleRegisterStubRequests_withHeader() {
 	simular.Activate()
 	defer simular.DeactivateAndReset()
 
@@ -1,14 +1,21 @@
 package simular
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 )
 
 // ErrNoResponderFound is a custom error type used when no responders were
-// found.
+// found. When returned by a MockTransport it describes the registered stubs
+// that most closely matched the request, and how they differed from it.
 type ErrNoResponderFound struct {
-	errs []error
+	errs       []error
+	request    string
+	url        *url.URL
+	rejections []*rejection
+	candidates []*Candidate
 }
 
 // Error ensures our ErrNoResponderFound type implements the error interface
@@ -17,6 +24,18 @@ func (e *ErrNoResponderFound) Error() string {
 		return "No responders found"
 	}
 
+	if len(e.candidates) > 0 {
+		closest := []string{}
+		for i, c := range e.candidates {
+			if i == maxCandidates {
+				break
+			}
+			closest = append(closest, c.String())
+		}
+
+		return fmt.Sprintf("No stub matched %s, closest stubs:\n\t%s", e.request, strings.Join(closest, "\n\t"))
+	}
+
 	errMsgs := []string{}
 	for _, e := range e.errs {
 		errMsgs = append(errMsgs, e.Error())
@@ -32,8 +51,14 @@ func NewErrNoResponderFound(errs []error) *ErrNoResponderFound {
 	}
 }
 
+// Candidates returns every registered stub considered for the unmatched
+// request, ordered from the closest match to the furthest.
+func (e *ErrNoResponderFound) Candidates() []*Candidate {
+	return e.candidates
+}
+
 // ErrStubsNotCalled is a type implementing the error interface we return when
-// not all registered stubs were called
+// not all registered stubs were called the expected number of times
 type ErrStubsNotCalled struct {
 	uncalledStubs []*StubRequest
 }
@@ -48,7 +73,13 @@ func (e *ErrStubsNotCalled) Error() string {
 
 	uncalled := []string{}
 	for _, s := range e.uncalledStubs {
-		uncalled = append(uncalled, s.String())
+		// stubs without explicit call expectations are simply listed
+		if s.expectedCalls == nil {
+			uncalled = append(uncalled, s.String())
+			continue
+		}
+
+		uncalled = append(uncalled, fmt.Sprintf("%s (%s)", s, s.verifyCalls()))
 	}
 
 	return fmt.Sprintf("Uncalled stubs: %s", strings.Join(uncalled, ", "))
@@ -60,3 +91,177 @@ func NewErrStubsNotCalled(uncalledStubs []*StubRequest) *ErrStubsNotCalled {
 		uncalledStubs: uncalledStubs,
 	}
 }
+
+// Errors returns the first reason each registered stub failed to match the
+// request, in the order the stubs were registered.
+func (e *ErrNoResponderFound) Errors() []error {
+	return append([]error(nil), e.errs...)
+}
+
+// Unwrap returns every reason each registered stub failed to match the
+// request, in the order the stubs were registered.
+func (e *ErrNoResponderFound) Unwrap() []error {
+	return e.allErrors()
+}
+
+// Is reports whether any reason a registered stub failed to match is target,
+// e.g. errors.Is(err, simular.ErrHeaderMismatch) reports whether any stub was
+// rejected because of the request's headers, even if it also failed to match
+// for other reasons. errors.Is only follows Unwrap() []error from Go 1.20, so
+// we walk the reasons ourselves.
+func (e *ErrNoResponderFound) Is(target error) bool {
+	for _, err := range e.allErrors() {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first reason a registered stub failed to match that matches
+// target, e.g. a *MismatchError, *ScenarioMismatchError or *ExhaustedError,
+// setting target to it.
+func (e *ErrNoResponderFound) As(target interface{}) bool {
+	for _, err := range e.allErrors() {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allErrors returns every reason each stub failed to match, or just the
+// errors the ErrNoResponderFound was created with if it wasn't returned by a
+// MockTransport.
+func (e *ErrNoResponderFound) allErrors() []error {
+	if len(e.rejections) == 0 {
+		return e.errs
+	}
+
+	errs := []error{}
+	for _, r := range e.rejections {
+		errs = append(errs, r.errs...)
+	}
+
+	return errs
+}
+
+// ScenarioMismatchError is the reason a stub was rejected when its scenario
+// was not in the state required by the stub.
+type ScenarioMismatchError struct {
+	Scenario string
+	Expected string
+	Actual   string
+}
+
+// Error ensures our ScenarioMismatchError type implements the error interface
+func (e *ScenarioMismatchError) Error() string {
+	return fmt.Sprintf("Unexpected scenario state, scenario %s expected %s, got %s", e.Scenario, e.Expected, e.Actual)
+}
+
+// ExhaustedError is the reason a stub which otherwise matched a request was
+// rejected, as it had already been called the maximum number of times it
+// expects.
+type ExhaustedError struct {
+	Stub  *StubRequest
+	Calls int
+}
+
+// Error ensures our ExhaustedError type implements the error interface
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("Exhausted stub %s, already called %d times", e.Stub, e.Calls)
+}
+
+// Stubs returns the registered stubs that were not called the expected number
+// of times.
+func (e *ErrStubsNotCalled) Stubs() []*StubRequest {
+	return append([]*StubRequest(nil), e.uncalledStubs...)
+}
+
+// MismatchKind identifies the part of a request that didn't match a stub
+type MismatchKind string
+
+const (
+	// MismatchMethod is the kind of a MismatchError caused by the request method
+	MismatchMethod MismatchKind = "method"
+
+	// MismatchURL is the kind of a MismatchError caused by the request URL
+	MismatchURL MismatchKind = "url"
+
+	// MismatchHeader is the kind of a MismatchError caused by a request header
+	MismatchHeader MismatchKind = "header"
+
+	// MismatchQuery is the kind of a MismatchError caused by a query parameter
+	MismatchQuery MismatchKind = "query"
+
+	// MismatchBody is the kind of a MismatchError caused by the request body
+	MismatchBody MismatchKind = "body"
+
+	// MismatchCustom is the kind of a MismatchError returned by a Matcher
+	MismatchCustom MismatchKind = "custom"
+)
+
+// Sentinel errors matched by errors.Is for each kind of MismatchError
+var (
+	ErrMethodMismatch = errors.New("method mismatch")
+	ErrURLMismatch    = errors.New("url mismatch")
+	ErrHeaderMismatch = errors.New("header mismatch")
+	ErrQueryMismatch  = errors.New("query mismatch")
+	ErrBodyMismatch   = errors.New("body mismatch")
+	ErrCustomMismatch = errors.New("custom matcher mismatch")
+)
+
+var mismatchSentinels = map[MismatchKind]error{
+	MismatchMethod: ErrMethodMismatch,
+	MismatchURL:    ErrURLMismatch,
+	MismatchHeader: ErrHeaderMismatch,
+	MismatchQuery:  ErrQueryMismatch,
+	MismatchBody:   ErrBodyMismatch,
+	MismatchCustom: ErrCustomMismatch,
+}
+
+// MismatchError is returned by StubRequest.Matches when a request doesn't
+// match a stub. Name holds the header or query parameter name for header and
+// query mismatches, and Expected and Actual hold the stub's and request's
+// values where these are known. Errors returned by a Matcher are wrapped in a
+// MismatchError of kind MismatchCustom, and can be retrieved with Unwrap.
+type MismatchError struct {
+	Kind     MismatchKind
+	Name     string
+	Expected string
+	Actual   string
+	Err      error
+
+	msg string
+}
+
+// mismatchf returns a new MismatchError with a formatted message
+func mismatchf(kind MismatchKind, name, expected, actual string, format string, args ...interface{}) *MismatchError {
+	return &MismatchError{
+		Kind:     kind,
+		Name:     name,
+		Expected: expected,
+		Actual:   actual,
+		msg:      fmt.Sprintf(format, args...),
+	}
+}
+
+// Error ensures our MismatchError type implements the error interface
+func (e *MismatchError) Error() string {
+	if e.msg == "" && e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.msg
+}
+
+// Is reports whether target is the sentinel error for this kind of mismatch
+func (e *MismatchError) Is(target error) bool {
+	return target != nil && mismatchSentinels[e.Kind] == target
+}
+
+// Unwrap returns the error returned by a custom Matcher, if any
+func (e *MismatchError) Unwrap() error {
+	return e.Err
+}
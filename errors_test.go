@@ -0,0 +1,238 @@
+package simular
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestMismatchErrors(t *testing.T) {
+	testcases := []struct {
+		label    string
+		stub     *StubRequest
+		sentinel error
+		kind     MismatchKind
+		name     string
+		expected string
+		actual   string
+	}{
+		{
+			label:    "method",
+			stub:     NewStubRequest("POST", "http://example.com/a?page=1", nil),
+			sentinel: ErrMethodMismatch,
+			kind:     MismatchMethod,
+			expected: "POST",
+			actual:   "GET",
+		},
+		{
+			label:    "url",
+			stub:     NewStubRequest("GET", "http://example.com/b", nil),
+			sentinel: ErrURLMismatch,
+			kind:     MismatchURL,
+			expected: "http://example.com/b",
+			actual:   "http://example.com/a?page=1",
+		},
+		{
+			label:    "header",
+			stub:     NewStubRequest("GET", "http://example.com/a?page=1", nil, WithHeader(&http.Header{"X-Api-Key": []string{"secret"}})),
+			sentinel: ErrHeaderMismatch,
+			kind:     MismatchHeader,
+			name:     "X-Api-Key",
+			expected: "secret",
+			actual:   "wrong",
+		},
+		{
+			label:    "query",
+			stub:     NewStubRequest("GET", "http://example.com/a", nil, WithQueryParams(url.Values{"page": []string{"2"}})),
+			sentinel: ErrQueryMismatch,
+			kind:     MismatchQuery,
+			name:     "page",
+			expected: "2",
+			actual:   "1",
+		},
+		{
+			label:    "body",
+			stub:     NewStubRequest("GET", "http://example.com/a?page=1", nil, WithBody(bytes.NewBufferString("expected"))),
+			sentinel: ErrBodyMismatch,
+			kind:     MismatchBody,
+			expected: "expected",
+			actual:   "actual",
+		},
+	}
+
+	for _, testcase := range testcases {
+		req, _ := http.NewRequest("GET", "http://example.com/a?page=1", bytes.NewBufferString("actual"))
+		req.Header.Set("X-Api-Key", "wrong")
+
+		err := testcase.stub.Matches(req)
+		if !errors.Is(err, testcase.sentinel) {
+			t.Errorf("%s: expected error to be %v, got %v", testcase.label, testcase.sentinel, err)
+		}
+
+		var mismatch *MismatchError
+		if !errors.As(err, &mismatch) {
+			t.Fatalf("%s: expected MismatchError, got %T", testcase.label, err)
+		}
+
+		if mismatch.Kind != testcase.kind {
+			t.Errorf("%s: unexpected kind, expected %s, got %s", testcase.label, testcase.kind, mismatch.Kind)
+		}
+
+		if mismatch.Name != testcase.name {
+			t.Errorf("%s: unexpected name, expected %s, got %s", testcase.label, testcase.name, mismatch.Name)
+		}
+
+		if mismatch.Expected != testcase.expected || mismatch.Actual != testcase.actual {
+			t.Errorf("%s: unexpected values, expected %s/%s, got %s/%s", testcase.label, testcase.expected, testcase.actual, mismatch.Expected, mismatch.Actual)
+		}
+	}
+}
+
+func TestMismatchErrorCustomMatcher(t *testing.T) {
+	errForbidden := errors.New("forbidden")
+
+	stub := NewStubRequest("GET", "http://example.com/", nil, WithMatcher(MatcherFunc(func(req *http.Request) error {
+		return fmt.Errorf("checking tenant: %w", errForbidden)
+	})))
+
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+
+	err := stub.Matches(req)
+	if !errors.Is(err, ErrCustomMismatch) {
+		t.Errorf("Expected custom mismatch, got %v", err)
+	}
+
+	if !errors.Is(err, errForbidden) {
+		t.Errorf("Expected matcher error to be unwrapped, got %v", err)
+	}
+
+	if err.Error() != "checking tenant: forbidden" {
+		t.Errorf("Unexpected error message: %s", err)
+	}
+}
+
+func TestErrNoResponderFoundAccessors(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest("POST", "http://example.com/", NewStringResponder(200, "ok")),
+		NewStubRequest("GET", "http://example.com/", NewStringResponder(200, "ok"), WithHeader(&http.Header{"X-Api-Key": []string{"secret"}})),
+	)
+
+	_, err := client.Get("http://example.com/")
+	if err == nil {
+		t.Fatal("Expected error for unmatched request")
+	}
+
+	if !errors.Is(err, ErrMethodMismatch) || !errors.Is(err, ErrHeaderMismatch) {
+		t.Errorf("Expected method and header mismatches, got %v", err)
+	}
+
+	if errors.Is(err, ErrBodyMismatch) {
+		t.Errorf("Unexpected body mismatch in %v", err)
+	}
+
+	var noMatch *ErrNoResponderFound
+	if !errors.As(err, &noMatch) {
+		t.Fatalf("Expected ErrNoResponderFound, got %T", err)
+	}
+
+	if len(noMatch.Errors()) != 2 {
+		t.Errorf("Expected 2 errors, got %v", noMatch.Errors())
+	}
+
+	// errors.Is and errors.As don't follow Unwrap() []error before Go 1.20,
+	// so check the methods they rely on directly
+	if !noMatch.Is(ErrMethodMismatch) || noMatch.Is(ErrBodyMismatch) {
+		t.Errorf("Unexpected result from Is for %v", noMatch)
+	}
+
+	var mismatch *MismatchError
+	if !noMatch.As(&mismatch) || mismatch.Kind != MismatchMethod {
+		t.Errorf("Expected As to find the method mismatch, got %v", mismatch)
+	}
+}
+
+func TestErrStubsNotCalledStubs(t *testing.T) {
+	transport := NewMockTransport()
+
+	stub := NewStubRequest("GET", "http://example.com/", NewStringResponder(200, "ok"))
+	transport.RegisterStubRequests(stub)
+
+	err := transport.AllStubsCalled()
+
+	var notCalled *ErrStubsNotCalled
+	if !errors.As(err, &notCalled) {
+		t.Fatalf("Expected ErrStubsNotCalled, got %T", err)
+	}
+
+	if stubs := notCalled.Stubs(); len(stubs) != 1 || stubs[0] != stub {
+		t.Errorf("Unexpected uncalled stubs: %v", stubs)
+	}
+}
+
+func TestErrNoResponderFoundEveryReason(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", "http://example.com/users", NewStringResponder(200, "ok")),
+		NewStubRequest("GET", "http://example.com/accounts", NewStringResponder(200, "ok")),
+	)
+
+	// every stub fails on the method first, but also on the URL
+	_, err := client.Post("http://example.com/unknown", "text/plain", nil)
+	if err == nil {
+		t.Fatal("Expected error for unmatched request")
+	}
+
+	var noMatch *ErrNoResponderFound
+	if !errors.As(err, &noMatch) {
+		t.Fatalf("Expected ErrNoResponderFound, got %T", err)
+	}
+
+	if !noMatch.Is(ErrMethodMismatch) || !noMatch.Is(ErrURLMismatch) {
+		t.Errorf("Expected method and URL mismatches, got %v", noMatch)
+	}
+
+	if len(noMatch.Unwrap()) != 4 {
+		t.Errorf("Expected every reason to be unwrapped, got %v", noMatch.Unwrap())
+	}
+}
+
+func TestErrNoResponderFoundScenarioAndExhausted(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", "http://example.com/", NewStringResponder(200, "ok"), Once()),
+		NewStubRequest("GET", "http://example.com/", NewStringResponder(200, "ok"), WithScenario("checkout", "Paid", "")),
+	)
+
+	resp, err := client.Get("http://example.com/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	_, err = client.Get("http://example.com/")
+	if err == nil {
+		t.Fatal("Expected error for unmatched request")
+	}
+
+	var noMatch *ErrNoResponderFound
+	if !errors.As(err, &noMatch) {
+		t.Fatalf("Expected ErrNoResponderFound, got %T", err)
+	}
+
+	var exhausted *ExhaustedError
+	if !noMatch.As(&exhausted) || exhausted.Calls != 1 {
+		t.Errorf("Expected exhausted stub, got %v", exhausted)
+	}
+
+	var scenario *ScenarioMismatchError
+	if !noMatch.As(&scenario) || scenario.Scenario != "checkout" || scenario.Expected != "Paid" || scenario.Actual != ScenarioStarted {
+		t.Errorf("Unexpected scenario mismatch, got %+v", scenario)
+	}
+}
@@ -0,0 +1,172 @@
+package simular
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+)
+
+// ConnectionRefused is a Responder that fails in the same way as a real
+// transport dialing a host with nothing listening, returning a *net.OpError
+// wrapping syscall.ECONNREFUSED.
+func ConnectionRefused(req *http.Request) (*http.Response, error) {
+	return nil, &net.OpError{
+		Op:   "dial",
+		Net:  "tcp",
+		Addr: requestAddr(req),
+		Err:  os.NewSyscallError("connect", syscall.ECONNREFUSED),
+	}
+}
+
+// ConnectionReset is a Responder that fails in the same way as a real
+// transport whose connection is reset by the server before a response is
+// received, returning a *net.OpError wrapping syscall.ECONNRESET.
+func ConnectionReset(req *http.Request) (*http.Response, error) {
+	return nil, resetError(req)
+}
+
+// ConnectTimeout is a Responder that fails in the same way as a real
+// transport timing out while dialing a host, returning a *net.OpError whose
+// Timeout method returns true.
+func ConnectTimeout(req *http.Request) (*http.Response, error) {
+	return nil, &net.OpError{
+		Op:   "dial",
+		Net:  "tcp",
+		Addr: requestAddr(req),
+		Err:  os.ErrDeadlineExceeded,
+	}
+}
+
+// DNSNotFound is a Responder that fails in the same way as a real transport
+// requesting a host that doesn't exist, returning a *net.OpError wrapping a
+// *net.DNSError.
+func DNSNotFound(req *http.Request) (*http.Response, error) {
+	return nil, &net.OpError{
+		Op:  "dial",
+		Net: "tcp",
+		Err: &net.DNSError{
+			Err:        "no such host",
+			Name:       req.URL.Hostname(),
+			IsNotFound: true,
+		},
+	}
+}
+
+// TLSHandshakeFailure is a Responder that fails in the same way as a real
+// transport attempting a TLS handshake with a server that doesn't speak TLS,
+// returning a tls.RecordHeaderError.
+func TLSHandshakeFailure(req *http.Request) (*http.Response, error) {
+	return nil, tls.RecordHeaderError{
+		Msg: "first record does not look like a TLS handshake",
+	}
+}
+
+// EmptyResponse is a Responder that fails in the same way as a real transport
+// whose connection is closed by the server without sending any response,
+// returning io.EOF.
+func EmptyResponse(req *http.Request) (*http.Response, error) {
+	return nil, io.EOF
+}
+
+// EOFDuringHeaders is a Responder that fails in the same way as a real
+// transport whose connection is closed by the server part way through sending
+// the response headers, returning io.ErrUnexpectedEOF.
+func EOFDuringHeaders(req *http.Request) (*http.Response, error) {
+	return nil, io.ErrUnexpectedEOF
+}
+
+// NewFailingBodyResponder wraps a Responder so that reading the body of its
+// response returns the given error after n bytes have been read.
+func NewFailingBodyResponder(n int64, err error, responder Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		resp, respErr := responder(req)
+		if respErr != nil {
+			return nil, respErr
+		}
+
+		resp.Body = &failingBody{
+			body:      resp.Body,
+			remaining: n,
+			err:       err,
+		}
+
+		return resp, nil
+	}
+}
+
+// NewResetBodyResponder wraps a Responder so that the connection appears to
+// be reset by the server after n bytes of the response body have been read.
+func NewResetBodyResponder(n int64, responder Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		return NewFailingBodyResponder(n, resetError(req), responder)(req)
+	}
+}
+
+// failingBody is an io.ReadCloser returning an error once a given number of
+// bytes have been read.
+type failingBody struct {
+	body      io.ReadCloser
+	remaining int64
+	err       error
+}
+
+func (f *failingBody) Read(p []byte) (int, error) {
+	if f.remaining <= 0 {
+		return 0, f.err
+	}
+
+	if int64(len(p)) > f.remaining {
+		p = p[:f.remaining]
+	}
+
+	n, err := f.body.Read(p)
+	f.remaining -= int64(n)
+
+	// a body shorter than n bytes ends normally
+	return n, err
+}
+
+func (f *failingBody) Close() error {
+	return f.body.Close()
+}
+
+// resetError returns the error a real transport returns when its connection
+// is reset
+func resetError(req *http.Request) error {
+	return &net.OpError{
+		Op:   "read",
+		Net:  "tcp",
+		Addr: requestAddr(req),
+		Err:  os.NewSyscallError("read", syscall.ECONNRESET),
+	}
+}
+
+// requestAddr returns a net.Addr for the host of the given request, used
+// when constructing network errors.
+func requestAddr(req *http.Request) net.Addr {
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	return &hostAddr{net.JoinHostPort(req.URL.Hostname(), port)}
+}
+
+// hostAddr is a minimal net.Addr for an unresolved TCP host and port
+type hostAddr struct {
+	hostport string
+}
+
+func (h *hostAddr) Network() string {
+	return "tcp"
+}
+
+func (h *hostAddr) String() string {
+	return h.hostport
+}
@@ -0,0 +1,133 @@
+package simular
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestFaultResponders(t *testing.T) {
+	testcases := []struct {
+		label     string
+		responder Responder
+		check     func(err error) bool
+		timeout   bool
+	}{
+		{
+			label:     "connection refused",
+			responder: ConnectionRefused,
+			check: func(err error) bool {
+				return errors.Is(err, syscall.ECONNREFUSED) && strings.Contains(err.Error(), "dial tcp www.example.com:80: connect: connection refused")
+			},
+		},
+		{
+			label:     "connection reset",
+			responder: ConnectionReset,
+			check: func(err error) bool {
+				return errors.Is(err, syscall.ECONNRESET)
+			},
+		},
+		{
+			label:     "connect timeout",
+			responder: ConnectTimeout,
+			check: func(err error) bool {
+				var opErr *net.OpError
+				return errors.As(err, &opErr) && opErr.Op == "dial"
+			},
+			timeout: true,
+		},
+		{
+			label:     "dns not found",
+			responder: DNSNotFound,
+			check: func(err error) bool {
+				var dnsErr *net.DNSError
+				return errors.As(err, &dnsErr) && dnsErr.IsNotFound && dnsErr.Name == "www.example.com"
+			},
+		},
+		{
+			label:     "tls handshake failure",
+			responder: TLSHandshakeFailure,
+			check: func(err error) bool {
+				var tlsErr tls.RecordHeaderError
+				return errors.As(err, &tlsErr)
+			},
+		},
+		{
+			label:     "empty response",
+			responder: EmptyResponse,
+			check: func(err error) bool {
+				return errors.Is(err, io.EOF)
+			},
+		},
+		{
+			label:     "eof during headers",
+			responder: EOFDuringHeaders,
+			check: func(err error) bool {
+				return errors.Is(err, io.ErrUnexpectedEOF)
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.label, func(t *testing.T) {
+			transport, client := NewMockClient()
+			transport.RegisterStubRequests(NewStubRequest("GET", testURL, testcase.responder))
+
+			_, err := client.Get(testURL)
+			if err == nil {
+				t.Fatal("Expected error, got none")
+			}
+
+			if !testcase.check(err) {
+				t.Errorf("Unexpected error type, got %#v", err)
+			}
+
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() != testcase.timeout {
+				t.Errorf("Unexpected Timeout(), expected %v", testcase.timeout)
+			}
+		})
+	}
+}
+
+func TestFailingBodyResponders(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", testURL, NewResetBodyResponder(5, NewStringResponder(200, "hello world"))),
+		NewStubRequest("GET", "http://www.example.com/short", NewFailingBodyResponder(50, io.ErrUnexpectedEOF, NewStringResponder(200, "hello world"))),
+	)
+
+	resp, err := client.Get(testURL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "hello" {
+		t.Errorf("Expected 5 bytes of body, got '%s'", body)
+	}
+
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("Expected connection reset error, got %v", err)
+	}
+
+	resp, err = client.Get("http://www.example.com/short")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil || string(body) != "hello world" {
+		t.Errorf("Expected body shorter than limit to be read in full, got '%s' %v", body, err)
+	}
+}
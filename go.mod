@@ -1,6 +1,6 @@
 module github.com/thingful/simular
 
-go 1.12
+go 1.15
 
 require (
 	github.com/PuerkitoBio/purell v1.1.0
@@ -8,4 +8,5 @@ require (
 	github.com/goware/urlx v0.0.0-20170529152211-e22571dfbd21
 	golang.org/x/net v0.0.0-20171102191033-01c190206fbd
 	golang.org/x/text v0.0.0-20171102192421-88f656faf3f3
+	gopkg.in/yaml.v2 v2.4.0
 )
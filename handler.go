@@ -0,0 +1,182 @@
+package simular
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// NewHandlerResponder creates a Responder that serves requests in process
+// using the given http.Handler, such as an existing fake of a service or a
+// router, without starting a server. The handler runs in its own goroutine
+// and, as with a real server, the response is returned to the client as soon
+// as the handler writes its header or flushes, with the body streamed to the
+// client as it is written. Trailers are available once the body has been
+// read to the end.
+func NewHandlerResponder(handler http.Handler) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		pr, pw := io.Pipe()
+		w := &handlerResponseWriter{
+			header: http.Header{},
+			req:    req,
+			body:   pr,
+			pw:     pw,
+			ready:  make(chan struct{}),
+		}
+
+		go w.serve(handler, serverRequest(req))
+
+		select {
+		case <-w.ready:
+			return w.resp, w.err
+		case <-req.Context().Done():
+			pr.CloseWithError(req.Context().Err())
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// handlerResponseWriter is the http.ResponseWriter passed to handlers served
+// by NewHandlerResponder. It sends the response to the waiting Responder
+// once the header is written, and then streams the body through a pipe.
+type handlerResponseWriter struct {
+	header http.Header
+	req    *http.Request
+	body   *io.PipeReader
+	pw     *io.PipeWriter
+
+	// ready is closed once resp or err is set
+	ready chan struct{}
+	once  sync.Once
+	resp  *http.Response
+	err   error
+
+	wroteHeader bool
+}
+
+// serve runs the handler, completing the response once it returns
+func (w *handlerResponseWriter) serve(handler http.Handler, req *http.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("simular: handler panic serving %s: %v", req.URL, r)
+			w.send(nil, err)
+			w.pw.CloseWithError(err)
+		}
+	}()
+
+	handler.ServeHTTP(w, req)
+
+	w.WriteHeader(http.StatusOK)
+	w.setTrailers()
+	w.pw.Close()
+}
+
+// send passes the response, or an error, to the waiting Responder. Only the
+// first call has any effect.
+func (w *handlerResponseWriter) send(resp *http.Response, err error) {
+	w.once.Do(func() {
+		w.resp, w.err = resp, err
+		close(w.ready)
+	})
+}
+
+// Header returns the header map that will be sent by WriteHeader
+func (w *handlerResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader sends the response with the given status code and a snapshot
+// of the current headers to the client.
+func (w *handlerResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	resp := &http.Response{
+		Status:     statusLine(code),
+		StatusCode: code,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     cloneHeader(w.header),
+		Trailer:    http.Header{},
+		Body:       w.body,
+		Request:    w.req,
+	}
+	resp.ContentLength = bodyLength(resp)
+
+	// declare the trailers announced by the handler, which are filled in
+	// once the handler returns
+	for _, keys := range w.header["Trailer"] {
+		for _, key := range strings.Split(keys, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				resp.Trailer[http.CanonicalHeaderKey(key)] = nil
+			}
+		}
+	}
+
+	w.send(resp, nil)
+}
+
+// Write streams the given bytes to the client, blocking until they are read.
+// If the header hasn't been written a 200 status is sent first, detecting
+// the content type from the bytes if it isn't set.
+func (w *handlerResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.header.Get("Content-Type") == "" && w.header.Get("Transfer-Encoding") == "" {
+			w.header.Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.pw.Write(b)
+}
+
+// Flush sends the response to the client if the header hasn't been written.
+// Written bytes are passed directly to the client so there is nothing else
+// to flush.
+func (w *handlerResponseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
+
+// setTrailers copies the values of the declared trailers, and of any headers
+// set with the http.TrailerPrefix, from the header onto the response. This
+// happens before the body is closed, so the client sees them once it reads to
+// the end of the body.
+func (w *handlerResponseWriter) setTrailers() {
+	for key := range w.resp.Trailer {
+		w.resp.Trailer[key] = w.header[key]
+	}
+
+	for key, values := range w.header {
+		if strings.HasPrefix(key, http.TrailerPrefix) {
+			w.resp.Trailer[http.CanonicalHeaderKey(strings.TrimPrefix(key, http.TrailerPrefix))] = values
+		}
+	}
+}
+
+// serverRequest converts an outgoing client request into the form a handler
+// expects to receive from a server.
+func serverRequest(req *http.Request) *http.Request {
+	serverReq := req.WithContext(req.Context())
+
+	serverReq.RequestURI = req.URL.RequestURI()
+	serverReq.RemoteAddr = "192.0.2.1:1234"
+	serverReq.Header = cloneHeader(req.Header)
+	if serverReq.Header == nil {
+		serverReq.Header = http.Header{}
+	}
+
+	if serverReq.Host == "" {
+		serverReq.Host = req.URL.Host
+	}
+
+	if serverReq.Body == nil {
+		serverReq.Body = http.NoBody
+	}
+
+	return serverReq
+}
@@ -0,0 +1,112 @@
+package simular
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewHandlerResponder(t *testing.T) {
+	// the handler waits for the client to read the first chunk before
+	// finishing the response
+	firstChunkRead := make(chan struct{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/articles/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+
+		fmt.Fprintf(w, "%s %s %s ", r.Method, r.Host, r.RequestURI)
+		w.(http.Flusher).Flush()
+
+		select {
+		case <-firstChunkRead:
+		case <-time.After(time.Second):
+			fmt.Fprint(w, "timeout")
+			return
+		}
+
+		fmt.Fprintf(w, "%s", body)
+
+		w.Header().Set("X-Checksum", "abc")
+	})
+
+	transport, client := NewMockClient()
+	transport.RegisterStubRequests(
+		NewStubRequest("POST", "http://api.example.com/articles/**", NewHandlerResponder(mux)),
+	)
+
+	resp, err := client.Post("http://api.example.com/articles/1?draft=true", "text/plain", bytes.NewBufferString("hello"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	first := "POST api.example.com /articles/1?draft=true "
+	chunk := make([]byte, len(first))
+	if _, err := io.ReadFull(resp.Body, chunk); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(chunk) != first {
+		t.Errorf("Unexpected first chunk, expected '%s', got '%s'", first, chunk)
+	}
+
+	if resp.Trailer.Get("X-Checksum") != "" {
+		t.Errorf("Expected trailer to be unset until the body is read, got %v", resp.Trailer)
+	}
+
+	close(firstChunkRead)
+
+	rest, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := append(chunk, rest...)
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Unexpected status, expected 201, got %d", resp.StatusCode)
+	}
+
+	if resp.Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("Unexpected headers, got %v", resp.Header)
+	}
+
+	expected := "POST api.example.com /articles/1?draft=true hello"
+	if string(body) != expected {
+		t.Errorf("Unexpected body, expected '%s', got '%s'", expected, body)
+	}
+
+	if resp.Trailer.Get("X-Checksum") != "abc" {
+		t.Errorf("Expected trailer to be set, got %v", resp.Trailer)
+	}
+
+	if resp.Request == nil || resp.Request.URL.Path != "/articles/1" {
+		t.Errorf("Expected response to reference the request")
+	}
+}
+
+func TestNewHandlerResponderPanic(t *testing.T) {
+	transport, client := NewMockClient()
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", "http://api.example.com/panic", NewHandlerResponder(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))),
+	)
+
+	_, err := client.Get("http://api.example.com/panic")
+	if err == nil {
+		t.Error("Expected error from panicking handler")
+	}
+}
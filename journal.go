@@ -0,0 +1,100 @@
+package simular
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// JournalEntry records a single request received by a MockTransport, along
+// with the stub (if any) that matched it and the outcome of the request.
+type JournalEntry struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+
+	// Body is a buffered copy of the request body
+	Body []byte
+
+	// Time is the time at which the request was received
+	Time time.Time
+
+	// Stub is the stubbed request that responded to this request, or nil if
+	// no stub matched
+	Stub *StubRequest
+
+	// StatusCode is the status of the response returned, or 0 if the request
+	// returned an error
+	StatusCode int
+
+	// Err is the error returned for the request, if any
+	Err error
+}
+
+// Matched returns true if a registered stub responded to the request
+func (e *JournalEntry) Matched() bool {
+	return e.Stub != nil
+}
+
+// newJournalEntry creates a JournalEntry for the given request. The request's
+// body is read and buffered once in order to do this, so we return a copy of
+// the request whose body reads from that buffer.
+func newJournalEntry(req *http.Request) (*http.Request, *JournalEntry, error) {
+	entry := &JournalEntry{
+		Method: req.Method,
+		URL:    cloneURL(req.URL),
+		Header: cloneHeader(req.Header),
+		Time:   time.Now(),
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, entry, nil
+	}
+
+	// copy the request so we don't modify the caller's request
+	req = req.WithContext(req.Context())
+
+	body, err := readBody(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entry.Body = body
+
+	return req, entry, nil
+}
+
+// cloneURL returns a copy of the given URL, so the entry isn't changed if the
+// caller modifies and reuses the request.
+func cloneURL(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+
+	clone := *u
+	if u.User != nil {
+		user := *u.User
+		clone.User = &user
+	}
+
+	return &clone
+}
+
+// copyJournal returns copies of the given entries, so they can be read while
+// requests are still being completed.
+func copyJournal(journal []*JournalEntry) []*JournalEntry {
+	entries := make([]*JournalEntry, 0, len(journal))
+	for _, entry := range journal {
+		e := *entry
+		entries = append(entries, &e)
+	}
+	return entries
+}
+
+// complete records the outcome of the request on the entry
+func (e *JournalEntry) complete(resp *http.Response, err error) {
+	e.Err = err
+	if resp != nil {
+		e.StatusCode = resp.StatusCode
+	}
+}
@@ -0,0 +1,207 @@
+package simular
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// jsonBody holds the decoded JSON document a stub expects as its request
+// body.
+type jsonBody struct {
+	expected interface{}
+	partial  bool
+}
+
+// WithJSONBody is a functional configuration option requiring the body of a
+// request to be a JSON document structurally equal to the given body, ignoring
+// differences in key order and whitespace. The body may be a string or byte
+// slice containing raw JSON, or any other value which is first encoded to
+// JSON.
+func WithJSONBody(body interface{}) Option {
+	return func(r *StubRequest) {
+		r.jsonBody = &jsonBody{expected: decodeJSONBody(body)}
+	}
+}
+
+// WithPartialJSONBody is a functional configuration option requiring the body
+// of a request to be a JSON document containing the given body. Objects on the
+// request may contain keys not present in the stub body, but arrays must have
+// the same length, with each element partially matching the corresponding
+// element on the request.
+func WithPartialJSONBody(body interface{}) Option {
+	return func(r *StubRequest) {
+		r.jsonBody = &jsonBody{expected: decodeJSONBody(body), partial: true}
+	}
+}
+
+// decodeJSONBody converts the given body into its generic decoded JSON form.
+// If the body can't be encoded or decoded we keep the error so it can be
+// reported when matching.
+func decodeJSONBody(body interface{}) interface{} {
+	var data []byte
+
+	switch b := body.(type) {
+	case string:
+		data = []byte(b)
+	case []byte:
+		data = b
+	default:
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		data = encoded
+	}
+
+	decoded, err := unmarshalJSON(data)
+	if err != nil {
+		return err
+	}
+
+	return decoded
+}
+
+// unmarshalJSON decodes the given JSON document into its generic form, with
+// numbers decoded as json.Number so that large integers aren't rounded to the
+// nearest float64.
+func unmarshalJSON(data []byte) (interface{}, error) {
+	// check the document first, so errors are reported as by json.Unmarshal
+	if err := json.Unmarshal(data, &json.RawMessage{}); err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
+
+// match compares the given request body with the expected JSON document,
+// returning an error describing the path of the first difference found.
+func (j *jsonBody) match(body []byte) error {
+	if err, ok := j.expected.(error); ok {
+		return fmt.Errorf("Invalid stub JSON body: %s", err)
+	}
+
+	actual, err := unmarshalJSON(body)
+	if err != nil {
+		return mismatchf(MismatchBody, "", "", string(body), "Unexpected JSON request body, unable to decode: %s", err)
+	}
+
+	if diff := jsonDiff("$", j.expected, actual, j.partial); diff != "" {
+		return mismatchf(MismatchBody, "", "", string(body), "Unexpected JSON request body, %s", diff)
+	}
+
+	return nil
+}
+
+// jsonDiff recursively compares two decoded JSON values, returning a
+// description of the first difference found or an empty string if they match.
+func jsonDiff(path string, expected, actual interface{}, partial bool) string {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return fmt.Sprintf("%s expected %s got %s", path, jsonString(expected), jsonString(actual))
+		}
+
+		for _, key := range sortedKeys(e) {
+			value, ok := a[key]
+			if !ok {
+				return fmt.Sprintf("%s.%s expected %s got nothing", path, key, jsonString(e[key]))
+			}
+
+			if diff := jsonDiff(path+"."+key, e[key], value, partial); diff != "" {
+				return diff
+			}
+		}
+
+		if !partial {
+			for _, key := range sortedKeys(a) {
+				if _, ok := e[key]; !ok {
+					return fmt.Sprintf("%s.%s unexpected, got %s", path, key, jsonString(a[key]))
+				}
+			}
+		}
+
+		return ""
+
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			return fmt.Sprintf("%s expected %s got %s", path, jsonString(expected), jsonString(actual))
+		}
+
+		if len(e) != len(a) {
+			return fmt.Sprintf("%s expected %d elements got %d", path, len(e), len(a))
+		}
+
+		for i := range e {
+			if diff := jsonDiff(fmt.Sprintf("%s[%d]", path, i), e[i], a[i], partial); diff != "" {
+				return diff
+			}
+		}
+
+		return ""
+
+	case json.Number:
+		a, ok := actual.(json.Number)
+		if !ok || !equalNumbers(e, a) {
+			return fmt.Sprintf("%s expected %s got %s", path, jsonString(expected), jsonString(actual))
+		}
+
+		return ""
+
+	default:
+		if expected != actual {
+			return fmt.Sprintf("%s expected %s got %s", path, jsonString(expected), jsonString(actual))
+		}
+
+		return ""
+	}
+}
+
+// equalNumbers returns true if the given JSON numbers have the same value,
+// comparing them exactly however they are written, e.g. 1, 1.0 and 1e0 are
+// equal.
+func equalNumbers(a, b json.Number) bool {
+	if a == b {
+		return true
+	}
+
+	x, okX := new(big.Rat).SetString(string(a))
+	y, okY := new(big.Rat).SetString(string(b))
+
+	return okX && okY && x.Cmp(y) == 0
+}
+
+// sortedKeys returns the keys of a decoded JSON object in sorted order, so
+// that differences are reported deterministically.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// jsonString returns the JSON encoding of a decoded value for use in error
+// messages.
+func jsonString(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(encoded)
+}
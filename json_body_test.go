@@ -0,0 +1,113 @@
+package simular
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func TestMatchesWithJSONBody(t *testing.T) {
+	testcases := []struct {
+		label       string
+		option      Option
+		requestBody string
+		expectedErr string
+	}{
+		{
+			label:       "different key order and whitespace",
+			option:      WithJSONBody(`{"title": "article", "tags": ["a", "b"]}`),
+			requestBody: `{"tags":["a","b"],"title":"article"}`,
+		},
+		{
+			label:       "encoded value",
+			option:      WithJSONBody(map[string]interface{}{"id": 5, "name": "foo"}),
+			requestBody: `{"name":"foo","id":5}`,
+		},
+		{
+			label:       "nested difference",
+			option:      WithJSONBody(`{"items": [{"id": 1}, {"id": 2}, {"id": 5}]}`),
+			requestBody: `{"items": [{"id": 1}, {"id": 2}, {"id": 6}]}`,
+			expectedErr: "Unexpected JSON request body, $.items[2].id expected 5 got 6",
+		},
+		{
+			label:       "unexpected key",
+			option:      WithJSONBody(`{"title": "article"}`),
+			requestBody: `{"title": "article", "draft": true}`,
+			expectedErr: "Unexpected JSON request body, $.draft unexpected, got true",
+		},
+		{
+			label:       "missing key",
+			option:      WithJSONBody(`{"title": "article", "author": {"name": "bob"}}`),
+			requestBody: `{"title": "article", "author": {}}`,
+			expectedErr: `Unexpected JSON request body, $.author.name expected "bob" got nothing`,
+		},
+		{
+			label:       "array length",
+			option:      WithJSONBody(`{"tags": ["a", "b"]}`),
+			requestBody: `{"tags": ["a"]}`,
+			expectedErr: "Unexpected JSON request body, $.tags expected 2 elements got 1",
+		},
+		{
+			label:       "type mismatch",
+			option:      WithJSONBody(`{"tags": ["a"]}`),
+			requestBody: `{"tags": "a"}`,
+			expectedErr: `Unexpected JSON request body, $.tags expected ["a"] got "a"`,
+		},
+		{
+			label:       "partial match",
+			option:      WithPartialJSONBody(`{"items": [{"id": 1}], "title": "article"}`),
+			requestBody: `{"items": [{"id": 1, "name": "foo"}], "title": "article", "draft": true}`,
+		},
+		{
+			label:       "partial mismatch",
+			option:      WithPartialJSONBody(`{"items": [{"id": 1}]}`),
+			requestBody: `{"items": [{"id": 2, "name": "foo"}], "draft": true}`,
+			expectedErr: "Unexpected JSON request body, $.items[0].id expected 1 got 2",
+		},
+		{
+			label:       "large integers",
+			option:      WithJSONBody(`{"id": 9007199254740993}`),
+			requestBody: `{"id": 9007199254740992}`,
+			expectedErr: "Unexpected JSON request body, $.id expected 9007199254740993 got 9007199254740992",
+		},
+		{
+			label:       "equal numbers written differently",
+			option:      WithJSONBody(map[string]interface{}{"price": 1.5, "count": 10}),
+			requestBody: `{"price": 1.50, "count": 1e1}`,
+		},
+		{
+			label:       "invalid request body",
+			option:      WithJSONBody(`{"title": "article"}`),
+			requestBody: `title=article`,
+			expectedErr: "Unexpected JSON request body, unable to decode: invalid character 'i' in literal true (expecting 'r')",
+		},
+		{
+			label:       "invalid stub body",
+			option:      WithJSONBody(`{"title": `),
+			requestBody: `{"title": "article"}`,
+			expectedErr: "Invalid stub JSON body: unexpected end of JSON input",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.label, func(t *testing.T) {
+			stub := NewStubRequest("POST", "http://example.com", NewStringResponder(200, "ok"), testcase.option)
+
+			req, err := http.NewRequest("POST", "http://example.com", bytes.NewBufferString(testcase.requestBody))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = stub.Matches(req)
+			if testcase.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error, got %s", err)
+				}
+			} else {
+				if err == nil || err.Error() != testcase.expectedErr {
+					t.Errorf("Unexpected error, expected '%s', got '%v'", testcase.expectedErr, err)
+				}
+			}
+		})
+	}
+}
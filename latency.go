@@ -0,0 +1,123 @@
+package simular
+
+import (
+	"math/rand"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Delay is a function returning how long a response should be delayed by. A
+// new duration is requested for every response, allowing delays to follow a
+// distribution.
+type Delay func() time.Duration
+
+// FixedDelay returns a Delay that always delays responses by the given
+// duration.
+func FixedDelay(d time.Duration) Delay {
+	return func() time.Duration {
+		return d
+	}
+}
+
+// UniformDelay returns a Delay that delays responses by a duration chosen
+// uniformly at random between min and max.
+func UniformDelay(min, max time.Duration) Delay {
+	return func() time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(rand.Int63n(int64(max-min)))
+	}
+}
+
+// NormalDelay returns a Delay that delays responses by a normally distributed
+// duration with the given mean and standard deviation. Negative durations are
+// treated as no delay.
+func NormalDelay(mean, stddev time.Duration) Delay {
+	return func() time.Duration {
+		d := time.Duration(rand.NormFloat64()*float64(stddev)) + mean
+		if d < 0 {
+			return 0
+		}
+		return d
+	}
+}
+
+// PercentileDelay returns a Delay whose durations follow the given
+// percentiles, for example:
+// 		simular.PercentileDelay(map[float64]time.Duration{
+// 			50: 20 * time.Millisecond,
+// 			95: 100 * time.Millisecond,
+// 			99: 500 * time.Millisecond,
+// 		})
+//
+// Durations between the given percentiles are linearly interpolated, starting
+// from no delay at the 0th percentile. Above the highest given percentile the
+// highest duration is used.
+func PercentileDelay(percentiles map[float64]time.Duration) Delay {
+	keys := make([]float64, 0, len(percentiles))
+	for p := range percentiles {
+		keys = append(keys, p)
+	}
+	sort.Float64s(keys)
+
+	return func() time.Duration {
+		p := rand.Float64() * 100
+
+		lowerP, lowerD := 0.0, time.Duration(0)
+		for _, upperP := range keys {
+			upperD := percentiles[upperP]
+			if p <= upperP {
+				if upperP == lowerP {
+					return upperD
+				}
+				fraction := (p - lowerP) / (upperP - lowerP)
+				return lowerD + time.Duration(fraction*float64(upperD-lowerD))
+			}
+			lowerP, lowerD = upperP, upperD
+		}
+
+		return lowerD
+	}
+}
+
+// NewDelayedResponder wraps a Responder so that its response is delayed by the
+// duration returned by delay. If the request's context is done before the
+// delay has passed (for example because the client's Timeout fired or the
+// request was cancelled), the context's error is returned immediately
+// instead, i.e. context.DeadlineExceeded or context.Canceled.
+func NewDelayedResponder(delay Delay, responder Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if err := wait(req, delay()); err != nil {
+			return nil, err
+		}
+		return responder(req)
+	}
+}
+
+// NoResponse is a Responder that never responds, blocking until the request
+// is cancelled or its deadline passes, at which point it returns the context's
+// error. This is useful for testing client timeouts.
+func NoResponse(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+// wait blocks for the given duration, returning early with an error if the
+// request's context is done first.
+func wait(req *http.Request, d time.Duration) error {
+	if d <= 0 {
+		return req.Context().Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,260 @@
+package simular
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDelays(t *testing.T) {
+	testcases := []struct {
+		label string
+		delay Delay
+		min   time.Duration
+		max   time.Duration
+	}{
+		{
+			"fixed",
+			FixedDelay(10 * time.Millisecond),
+			10 * time.Millisecond,
+			10 * time.Millisecond,
+		},
+		{
+			"uniform",
+			UniformDelay(10*time.Millisecond, 20*time.Millisecond),
+			10 * time.Millisecond,
+			20 * time.Millisecond,
+		},
+		{
+			"normal",
+			NormalDelay(10*time.Millisecond, 0),
+			10 * time.Millisecond,
+			10 * time.Millisecond,
+		},
+		{
+			"percentile",
+			PercentileDelay(map[float64]time.Duration{
+				50: 10 * time.Millisecond,
+				99: 100 * time.Millisecond,
+			}),
+			0,
+			100 * time.Millisecond,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.label, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				d := testcase.delay()
+				if d < testcase.min || d > testcase.max {
+					t.Fatalf("Delay %s outside expected range %s - %s", d, testcase.min, testcase.max)
+				}
+			}
+		})
+	}
+}
+
+func TestNewDelayedResponder(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", testURL, NewDelayedResponder(FixedDelay(10*time.Millisecond), NewStringResponder(200, "ok"))),
+	)
+
+	start := time.Now()
+
+	resp, err := client.Get(testURL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if time.Since(start) < 10*time.Millisecond {
+		t.Errorf("Expected response to be delayed")
+	}
+}
+
+func TestDelayedResponderClientTimeout(t *testing.T) {
+	transport, client := NewMockClient()
+	client.Timeout = 10 * time.Millisecond
+
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", testURL, NewDelayedResponder(FixedDelay(time.Minute), NewStringResponder(200, "ok"))),
+	)
+
+	start := time.Now()
+
+	_, err := client.Get(testURL)
+	if err == nil {
+		t.Fatal("Expected timeout error")
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Expected request to time out promptly")
+	}
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+}
+
+func TestNoResponseContextCancelled(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", testURL, NoResponse),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	req, err := http.NewRequest("GET", testURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err = client.Do(req.WithContext(ctx))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestMockTransportCancelRequest(t *testing.T) {
+	transport := NewMockTransport()
+
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", testURL, NoResponse),
+	)
+
+	req, err := http.NewRequest("GET", testURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error)
+	go func() {
+		_, err := transport.RoundTrip(req)
+		errs <- err
+	}()
+
+	// wait for the request to be in flight before cancelling it
+	for {
+		transport.mu.Lock()
+		_, ok := transport.inflight[req]
+		transport.mu.Unlock()
+		if ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	transport.CancelRequest(req)
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected request to be cancelled")
+	}
+}
+
+// blockingBody is a response body that blocks until its request is cancelled
+type blockingBody struct {
+	ctx context.Context
+}
+
+func (b *blockingBody) Read(p []byte) (int, error) {
+	<-b.ctx.Done()
+	return 0, b.ctx.Err()
+}
+
+func (b *blockingBody) Close() error {
+	return nil
+}
+
+func TestMockTransportCancelRequestBody(t *testing.T) {
+	transport := NewMockTransport()
+
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", testURL, func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: 200, Body: &blockingBody{req.Context()}}, nil
+		}),
+	)
+
+	req, err := http.NewRequest("GET", testURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// the request remains in flight while the body is being read
+	errs := make(chan error)
+	go func() {
+		_, err := ioutil.ReadAll(resp.Body)
+		errs <- err
+	}()
+
+	transport.CancelRequest(req)
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected reading the body to be cancelled")
+	}
+
+	resp.Body.Close()
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	if len(transport.inflight) != 0 {
+		t.Errorf("Expected request to be forgotten once its body was closed")
+	}
+}
+
+func TestMockTransportPassthroughBody(t *testing.T) {
+	// large enough that the body is still being streamed when RoundTrip returns
+	body := strings.Repeat("x", 10<<20)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	_, client := NewMockClient(AllowHosts("127.0.0.1"), WithPassthrough(&http.Transport{}))
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Unexpected error reading body: %v", err)
+	}
+
+	if len(data) != len(body) {
+		t.Errorf("Expected body of length %d, got %d", len(body), len(data))
+	}
+}
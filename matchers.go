@@ -0,0 +1,138 @@
+package simular
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Matcher is the interface implemented by types that can check whether an
+// incoming request satisfies some requirement of a stubbed request. Match
+// should return nil if the request is acceptable, or an error describing why
+// it isn't. Matchers may read the request body, as each matcher is given a
+// fresh copy.
+type Matcher interface {
+	Match(req *http.Request) error
+}
+
+// MatcherFunc is an adapter allowing an ordinary function to be used as a
+// Matcher.
+type MatcherFunc func(req *http.Request) error
+
+// Match calls f(req)
+func (f MatcherFunc) Match(req *http.Request) error {
+	return f(req)
+}
+
+// MatchHost returns a Matcher requiring the request's host (excluding any
+// port) to equal the given host, ignoring case.
+func MatchHost(host string) Matcher {
+	return MatcherFunc(func(req *http.Request) error {
+		if !strings.EqualFold(stripPort(req.URL.Host), host) {
+			return fmt.Errorf("Unexpected host, expected %s, got %s", host, stripPort(req.URL.Host))
+		}
+		return nil
+	})
+}
+
+// MatchPathPrefix returns a Matcher requiring the request's path to start with
+// the given prefix.
+func MatchPathPrefix(prefix string) Matcher {
+	return MatcherFunc(func(req *http.Request) error {
+		if !strings.HasPrefix(req.URL.Path, prefix) {
+			return fmt.Errorf("Unexpected path, expected prefix %s, got %s", prefix, req.URL.Path)
+		}
+		return nil
+	})
+}
+
+// MatchPathRegexp returns a Matcher requiring the request's path to match the
+// given regular expression.
+func MatchPathRegexp(pattern *regexp.Regexp) Matcher {
+	return MatcherFunc(func(req *http.Request) error {
+		if !pattern.MatchString(req.URL.Path) {
+			return fmt.Errorf("Unexpected path, expected path matching %s, got %s", pattern, req.URL.Path)
+		}
+		return nil
+	})
+}
+
+// MatchHeaderRegexp returns a Matcher requiring the named header to be present
+// on the request, with at least one value matching the given regular
+// expression.
+func MatchHeaderRegexp(name string, pattern *regexp.Regexp) Matcher {
+	return MatcherFunc(func(req *http.Request) error {
+		values := req.Header[http.CanonicalHeaderKey(name)]
+		for _, v := range values {
+			if pattern.MatchString(v) {
+				return nil
+			}
+		}
+		return fmt.Errorf("Unexpected header %s, expected value matching %s, got %v", name, pattern, values)
+	})
+}
+
+// MatchCookie returns a Matcher requiring the request to include a cookie with
+// the given name and value.
+func MatchCookie(name, value string) Matcher {
+	return MatcherFunc(func(req *http.Request) error {
+		cookie, err := req.Cookie(name)
+		if err != nil {
+			return fmt.Errorf("Missing cookie %s", name)
+		}
+
+		if cookie.Value != value {
+			return fmt.Errorf("Unexpected cookie %s, expected %s, got %s", name, value, cookie.Value)
+		}
+		return nil
+	})
+}
+
+// MatchBasicAuth returns a Matcher requiring the request to use HTTP basic
+// authentication with the given username and password.
+func MatchBasicAuth(username, password string) Matcher {
+	return MatcherFunc(func(req *http.Request) error {
+		u, p, ok := req.BasicAuth()
+		if !ok {
+			return fmt.Errorf("Missing basic auth credentials")
+		}
+
+		if u != username || p != password {
+			return fmt.Errorf("Unexpected basic auth credentials for user %s", u)
+		}
+		return nil
+	})
+}
+
+// MatchBearerToken returns a Matcher requiring the request to have an
+// Authorization header containing the given bearer token.
+func MatchBearerToken(token string) Matcher {
+	return MatcherFunc(func(req *http.Request) error {
+		auth := req.Header.Get("Authorization")
+		if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+			return fmt.Errorf("Missing bearer token")
+		}
+
+		if auth[7:] != token {
+			return fmt.Errorf("Unexpected bearer token")
+		}
+		return nil
+	})
+}
+
+// MatchContentType returns a Matcher requiring the request's Content-Type
+// header to have the given media type, ignoring any parameters such as
+// charset.
+func MatchContentType(mediaType string) Matcher {
+	return MatcherFunc(func(req *http.Request) error {
+		contentType := req.Header.Get("Content-Type")
+
+		got, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !strings.EqualFold(got, mediaType) {
+			return fmt.Errorf("Unexpected content type, expected %s, got %s", mediaType, contentType)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,175 @@
+package simular
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestMatchers(t *testing.T) {
+	testcases := []struct {
+		label       string
+		matcher     Matcher
+		setup       func(req *http.Request)
+		expectedErr bool
+	}{
+		{
+			label:   "host",
+			matcher: MatchHost("EXAMPLE.com"),
+		},
+		{
+			label:       "wrong host",
+			matcher:     MatchHost("another.com"),
+			expectedErr: true,
+		},
+		{
+			label:   "path prefix",
+			matcher: MatchPathPrefix("/api/"),
+		},
+		{
+			label:       "wrong path prefix",
+			matcher:     MatchPathPrefix("/v2/"),
+			expectedErr: true,
+		},
+		{
+			label:   "path regexp",
+			matcher: MatchPathRegexp(regexp.MustCompile(`^/api/users/\d+$`)),
+		},
+		{
+			label:       "wrong path regexp",
+			matcher:     MatchPathRegexp(regexp.MustCompile(`^/api/orders/`)),
+			expectedErr: true,
+		},
+		{
+			label:   "header regexp",
+			matcher: MatchHeaderRegexp("x-request-id", regexp.MustCompile(`^[a-f0-9]+$`)),
+			setup: func(req *http.Request) {
+				req.Header.Set("X-Request-Id", "abc123")
+			},
+		},
+		{
+			label:       "missing header regexp",
+			matcher:     MatchHeaderRegexp("X-Request-Id", regexp.MustCompile(`^[a-f0-9]+$`)),
+			expectedErr: true,
+		},
+		{
+			label:   "cookie",
+			matcher: MatchCookie("session", "abc"),
+			setup: func(req *http.Request) {
+				req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+			},
+		},
+		{
+			label:   "wrong cookie",
+			matcher: MatchCookie("session", "abc"),
+			setup: func(req *http.Request) {
+				req.AddCookie(&http.Cookie{Name: "session", Value: "def"})
+			},
+			expectedErr: true,
+		},
+		{
+			label:   "basic auth",
+			matcher: MatchBasicAuth("user", "secret"),
+			setup: func(req *http.Request) {
+				req.SetBasicAuth("user", "secret")
+			},
+		},
+		{
+			label:   "wrong basic auth",
+			matcher: MatchBasicAuth("user", "secret"),
+			setup: func(req *http.Request) {
+				req.SetBasicAuth("user", "guess")
+			},
+			expectedErr: true,
+		},
+		{
+			label:   "bearer token",
+			matcher: MatchBearerToken("token"),
+			setup: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer token")
+			},
+		},
+		{
+			label:   "wrong bearer token",
+			matcher: MatchBearerToken("token"),
+			setup: func(req *http.Request) {
+				req.SetBasicAuth("user", "token")
+			},
+			expectedErr: true,
+		},
+		{
+			label:   "content type",
+			matcher: MatchContentType("application/json"),
+			setup: func(req *http.Request) {
+				req.Header.Set("Content-Type", "application/json; charset=utf-8")
+			},
+		},
+		{
+			label:       "missing content type",
+			matcher:     MatchContentType("application/json"),
+			expectedErr: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.label, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "http://example.com:8080/api/users/1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if testcase.setup != nil {
+				testcase.setup(req)
+			}
+
+			err = testcase.matcher.Match(req)
+			if testcase.expectedErr && err == nil {
+				t.Errorf("Expected error, got none")
+			} else if !testcase.expectedErr && err != nil {
+				t.Errorf("Unexpected error, got %s", err)
+			}
+		})
+	}
+}
+
+func TestMatchesWithMatcher(t *testing.T) {
+	readsBody := MatcherFunc(func(req *http.Request) error {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+
+		if string(body) != "hello" {
+			return errors.New("unexpected body")
+		}
+		return nil
+	})
+
+	stub := NewStubRequest(
+		"",
+		"",
+		NewStringResponder(200, "ok"),
+		WithMatcher(MatchHost("example.com"), readsBody, readsBody),
+	)
+
+	req, err := http.NewRequest("PUT", "http://example.com/anything", bytes.NewBufferString("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stub.Matches(req); err != nil {
+		t.Errorf("Unexpected error, got %s", err)
+	}
+
+	req, err = http.NewRequest("PUT", "http://another.com/anything", bytes.NewBufferString("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stub.Matches(req); err == nil {
+		t.Errorf("Expected error for wrong host, got none")
+	}
+}
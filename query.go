@@ -0,0 +1,145 @@
+package simular
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goware/urlx"
+)
+
+// queryMatcher is a function that checks the query parameters of an incoming
+// request, returning an error describing any mismatch.
+type queryMatcher func(query url.Values) error
+
+// WithQuery is a functional configuration option requiring the query
+// parameters of a request to be exactly the given set of parameters. The order
+// of parameters (and of multiple values for a parameter) is ignored.
+//
+// Setting any query option on a stub means the query string is no longer
+// compared as part of the URL; instead any parameters present on the stub's
+// URL are required to be present on the request.
+func WithQuery(values url.Values) Option {
+	return func(r *StubRequest) {
+		r.queryMatchers = append(r.queryMatchers, func(query url.Values) error {
+			for name := range query {
+				if _, ok := values[name]; !ok {
+					return mismatchf(MismatchQuery, name, "", strings.Join(query[name], ", "), "Unexpected query parameter %s", name)
+				}
+			}
+			return requireQueryValues(values, query, true)
+		})
+	}
+}
+
+// WithQueryParams is a functional configuration option requiring the given
+// query parameters to be present on a request. Any other parameters on the
+// request are ignored.
+func WithQueryParams(values url.Values) Option {
+	return func(r *StubRequest) {
+		r.queryMatchers = append(r.queryMatchers, func(query url.Values) error {
+			return requireQueryValues(values, query, false)
+		})
+	}
+}
+
+// WithQueryParamRegexp is a functional configuration option requiring the
+// named query parameter to be present on a request, with every value of the
+// parameter matching the given regular expression.
+func WithQueryParamRegexp(name string, pattern *regexp.Regexp) Option {
+	return func(r *StubRequest) {
+		r.queryMatchers = append(r.queryMatchers, func(query url.Values) error {
+			values, ok := query[name]
+			if !ok {
+				return mismatchf(MismatchQuery, name, pattern.String(), "", "Missing query parameter %s", name)
+			}
+
+			for _, v := range values {
+				if !pattern.MatchString(v) {
+					return mismatchf(MismatchQuery, name, pattern.String(), v, "Unexpected query parameter %s, expected value matching %s, got %s", name, pattern, v)
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// WithoutQueryParams is a functional configuration option forbidding the
+// given query parameters from being present on a request.
+func WithoutQueryParams(names ...string) Option {
+	return func(r *StubRequest) {
+		r.queryMatchers = append(r.queryMatchers, func(query url.Values) error {
+			for _, name := range names {
+				if _, ok := query[name]; ok {
+					return mismatchf(MismatchQuery, name, "", strings.Join(query[name], ", "), "Forbidden query parameter %s present", name)
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// requireQueryValues checks that every parameter in expected is present in
+// query with the same values. If exact is true the values must be identical
+// sets, otherwise the request may contain additional values.
+func requireQueryValues(expected, query url.Values, exact bool) error {
+	for name, expectedValues := range expected {
+		values, ok := query[name]
+		if !ok {
+			return mismatchf(MismatchQuery, name, strings.Join(expectedValues, ", "), "", "Missing query parameter %s", name)
+		}
+
+		if exact && !equalUnordered(expectedValues, values) {
+			return mismatchf(MismatchQuery, name, strings.Join(expectedValues, ", "), strings.Join(values, ", "), "Unexpected query parameter %s, expected %v, got %v", name, expectedValues, values)
+		}
+
+		for _, v := range expectedValues {
+			if !contains(values, v) {
+				return mismatchf(MismatchQuery, name, strings.Join(expectedValues, ", "), strings.Join(values, ", "), "Unexpected query parameter %s, expected %v, got %v", name, expectedValues, values)
+			}
+		}
+	}
+
+	return nil
+}
+
+// equalUnordered returns true if the two slices contain the same strings,
+// ignoring their order
+func equalUnordered(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitQuery parses the given url, returning the normalized url without its
+// query string along with the parsed query parameters.
+func splitQuery(rawurl string) (string, url.Values, error) {
+	u, err := urlx.Parse(rawurl)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := u.Query()
+	u.RawQuery = ""
+
+	normalized, err := urlx.Normalize(u)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return normalized, query, nil
+}
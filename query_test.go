@@ -0,0 +1,110 @@
+package simular
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMatchesWithQuery(t *testing.T) {
+	testcases := []struct {
+		stubURL     string
+		options     []Option
+		requestURL  string
+		expectedErr string
+	}{
+		{
+			stubURL:    "http://example.com/search",
+			options:    []Option{WithQuery(url.Values{"q": {"foo"}, "tag": {"a", "b"}})},
+			requestURL: "http://example.com/search?tag=b&q=foo&tag=a",
+		},
+		{
+			stubURL:     "http://example.com/search",
+			options:     []Option{WithQuery(url.Values{"q": {"foo"}})},
+			requestURL:  "http://example.com/search?q=foo&page=2",
+			expectedErr: "Unexpected query parameter page",
+		},
+		{
+			stubURL:     "http://example.com/search",
+			options:     []Option{WithQuery(url.Values{"tag": {"a", "b"}})},
+			requestURL:  "http://example.com/search?tag=a",
+			expectedErr: "Unexpected query parameter tag, expected [a b], got [a]",
+		},
+		{
+			stubURL:    "http://example.com/search",
+			options:    []Option{WithQueryParams(url.Values{"api_key": {"X"}})},
+			requestURL: "http://example.com/search?q=foo&api_key=X&page=2",
+		},
+		{
+			stubURL:     "http://example.com/search",
+			options:     []Option{WithQueryParams(url.Values{"api_key": {"X"}})},
+			requestURL:  "http://example.com/search?q=foo",
+			expectedErr: "Missing query parameter api_key",
+		},
+		{
+			stubURL:     "http://example.com/search",
+			options:     []Option{WithQueryParams(url.Values{"api_key": {"X"}})},
+			requestURL:  "http://example.com/search?api_key=Y",
+			expectedErr: "Unexpected query parameter api_key, expected [X], got [Y]",
+		},
+		{
+			stubURL:    "http://example.com/search?api_key=X",
+			options:    []Option{WithoutQueryParams("debug")},
+			requestURL: "http://example.com/search?api_key=X&q=foo",
+		},
+		{
+			stubURL:     "http://example.com/search?api_key=X",
+			options:     []Option{WithoutQueryParams("debug")},
+			requestURL:  "http://example.com/search?q=foo",
+			expectedErr: "Missing query parameter api_key",
+		},
+		{
+			stubURL:     "http://example.com/search",
+			options:     []Option{WithoutQueryParams("debug")},
+			requestURL:  "http://example.com/search?debug=1",
+			expectedErr: "Forbidden query parameter debug present",
+		},
+		{
+			stubURL:    "http://example.com/search",
+			options:    []Option{WithQueryParamRegexp("page", regexp.MustCompile(`^\d+$`))},
+			requestURL: "http://example.com/search?page=12",
+		},
+		{
+			stubURL:     "http://example.com/search",
+			options:     []Option{WithQueryParamRegexp("page", regexp.MustCompile(`^\d+$`))},
+			requestURL:  "http://example.com/search?page=last",
+			expectedErr: `Unexpected query parameter page, expected value matching ^\d+$, got last`,
+		},
+		{
+			stubURL:     "http://example.com/search",
+			options:     []Option{WithoutQueryParams("debug")},
+			requestURL:  "http://example.com/other",
+			expectedErr: "Unexpected URL",
+		},
+	}
+
+	for i, testcase := range testcases {
+		t.Run(fmt.Sprintf("%d %s", i, testcase.requestURL), func(t *testing.T) {
+			stub := NewStubRequest("GET", testcase.stubURL, NewStringResponder(200, "ok"), testcase.options...)
+
+			req, err := http.NewRequest("GET", testcase.requestURL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = stub.Matches(req)
+			if testcase.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error, got %s", err)
+				}
+			} else {
+				if err == nil || !strings.HasPrefix(err.Error(), testcase.expectedErr) {
+					t.Errorf("Unexpected error, expected '%s', got '%v'", testcase.expectedErr, err)
+				}
+			}
+		})
+	}
+}
@@ -4,28 +4,66 @@ import (
 	"bytes"
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-// ResponderFromResponse wraps an *http.Response in a Responder
+// ResponderFromResponse wraps an *http.Response in a Responder. Each call
+// returns a copy of the response with its own header and body, so the client
+// may modify or read the response without affecting any other request,
+// including concurrent ones. The body of the given response is read in full
+// the first time the Responder is called.
 func ResponderFromResponse(resp *http.Response) Responder {
+	var once sync.Once
+	var body []byte
+	var err error
+
 	return func(req *http.Request) (*http.Response, error) {
-		return resp, nil
+		once.Do(func() {
+			body, err = readResponseBody(resp)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return copyResponse(resp, body), nil
+	}
+}
+
+// readResponseBody reads and closes the body of the given response
+func readResponseBody(resp *http.Response) ([]byte, error) {
+	if resp.Body == nil {
+		return nil, nil
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// copyResponse returns a copy of the given response with copied headers, and
+// a new body reading from the given bytes.
+func copyResponse(resp *http.Response, body []byte) *http.Response {
+	clone := *resp
+	clone.Header = cloneHeader(resp.Header)
+	clone.Trailer = cloneHeader(resp.Trailer)
+
+	if resp.Body != nil {
+		clone.Body = NewRespBodyFromBytes(body)
 	}
+
+	return &clone
 }
 
 // NewStringResponse creates an *http.Response with a body based on the given string.  Also accepts
 // an http status code.
 func NewStringResponse(status int, body string) *http.Response {
-	return &http.Response{
-		Status:     strconv.Itoa(status),
-		StatusCode: status,
-		Body:       NewRespBodyFromString(body),
-		Header:     http.Header{},
-	}
+	return newResponse(status, NewRespBodyFromString(body))
 }
 
 // NewStringResponder creates a Responder from a given body (as a string) and status code.
@@ -36,14 +74,94 @@ func NewStringResponder(status int, body string) Responder {
 // NewBytesResponse creates an *http.Response with a body based on the given bytes.  Also accepts
 // an http status code.
 func NewBytesResponse(status int, body []byte) *http.Response {
+	return newResponse(status, NewRespBodyFromBytes(body))
+}
+
+// newResponse creates an HTTP/1.1 *http.Response with the given status and
+// body. The content length is filled in by populateResponse once the
+// response is returned by a MockTransport.
+func newResponse(status int, body io.ReadCloser) *http.Response {
 	return &http.Response{
-		Status:     strconv.Itoa(status),
+		Status:     statusLine(status),
 		StatusCode: status,
-		Body:       NewRespBodyFromBytes(body),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Body:       body,
 		Header:     http.Header{},
 	}
 }
 
+// statusLine returns the status of a response with the given status code as
+// returned by a real server, e.g. "200 OK".
+func statusLine(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		text = "status code " + strconv.Itoa(status)
+	}
+	return fmt.Sprintf("%d %s", status, text)
+}
+
+// populateResponse returns a copy of the response generated for the given
+// request, with any fields a real transport would always set filled in: the
+// status text, protocol, originating request, body, content length and the
+// Content-Length and Date headers. Fields already set are left untouched.
+func populateResponse(req *http.Request, resp *http.Response) *http.Response {
+	populated := *resp
+	populated.Header = cloneHeader(resp.Header)
+	if populated.Header == nil {
+		populated.Header = http.Header{}
+	}
+
+	if populated.Status == "" || populated.Status == strconv.Itoa(populated.StatusCode) {
+		populated.Status = statusLine(populated.StatusCode)
+	}
+
+	if populated.Proto == "" {
+		populated.Proto, populated.ProtoMajor, populated.ProtoMinor = "HTTP/1.1", 1, 1
+	}
+
+	populated.Request = req
+
+	if populated.Body == nil {
+		populated.Body = http.NoBody
+	}
+
+	if populated.ContentLength == 0 {
+		populated.ContentLength = bodyLength(&populated)
+	}
+
+	if populated.ContentLength >= 0 && populated.Header.Get("Content-Length") == "" {
+		populated.Header.Set("Content-Length", strconv.FormatInt(populated.ContentLength, 10))
+	}
+
+	if populated.Header.Get("Date") == "" {
+		populated.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	return &populated
+}
+
+// bodyLength returns the length of the response body if it can be known
+// without reading it, or -1 if not.
+func bodyLength(resp *http.Response) int64 {
+	if resp.Body == http.NoBody {
+		return 0
+	}
+
+	if body, ok := resp.Body.(*dummyReadCloser); ok {
+		if sized, ok := body.body.(interface{ Size() int64 }); ok {
+			return sized.Size()
+		}
+	}
+
+	if n, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		return n
+	}
+
+	return -1
+}
+
 // NewBytesResponder creates a Responder from a given body (as a byte slice) and status code.
 func NewBytesResponder(status int, body []byte) Responder {
 	return ResponderFromResponse(NewBytesResponse(status, body))
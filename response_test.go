@@ -5,6 +5,8 @@ import (
 	"encoding/xml"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -145,3 +147,176 @@ func TestRewindResponse(t *testing.T) {
 		}
 	}
 }
+
+func TestResponderFromResponse(t *testing.T) {
+	original := NewStringResponse(200, "hello world")
+	original.Header.Set("Content-Type", "text/plain")
+
+	responder := ResponderFromResponse(original)
+
+	first, err := responder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := responder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second || first.Body == second.Body {
+		t.Fatal("Expected a new response for each call")
+	}
+
+	first.Header.Set("Content-Type", "application/json")
+	if second.Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("Expected headers to be copied, got %s", second.Header.Get("Content-Type"))
+	}
+
+	// partially read the first body, which shouldn't affect the second
+	buf := make([]byte, 5)
+	first.Body.Read(buf)
+
+	data, err := ioutil.ReadAll(second.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "hello world" {
+		t.Errorf("Unexpected body, got %s", data)
+	}
+}
+
+func TestResponderFromResponseConcurrent(t *testing.T) {
+	body := strings.Repeat("hello world ", 1000)
+	responder := NewStringResponder(200, body)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp, err := responder(nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			data, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if string(data) != body {
+				t.Errorf("Unexpected body of length %d", len(data))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestResponderFromResponseBody(t *testing.T) {
+	original := &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader("streamed")),
+	}
+
+	responder := ResponderFromResponse(original)
+
+	for i := 0; i < 2; i++ {
+		resp, err := responder(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != "streamed" {
+			t.Errorf("Unexpected body on call %d, got %s", i, data)
+		}
+	}
+}
+
+func TestNewResponseFields(t *testing.T) {
+	response := NewStringResponse(404, "not found")
+
+	if response.Status != "404 Not Found" {
+		t.Errorf("Unexpected status, got %s", response.Status)
+	}
+
+	if response.Proto != "HTTP/1.1" || response.ProtoMajor != 1 || response.ProtoMinor != 1 {
+		t.Errorf("Unexpected protocol, got %s", response.Proto)
+	}
+
+	// the content length is only filled in once returned by a transport
+	if response.ContentLength != 0 || response.Header.Get("Content-Length") != "" {
+		t.Errorf("Unexpected content length, got %d and %s", response.ContentLength, response.Header.Get("Content-Length"))
+	}
+
+	if status := NewBytesResponse(599, nil).Status; status != "599 status code 599" {
+		t.Errorf("Unexpected status for unknown code, got %s", status)
+	}
+}
+
+func TestMockTransportPopulatesResponse(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", "http://example.com/string", NewStringResponder(201, "created")),
+		NewStubRequest("GET", "http://example.com/custom", func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("custom")),
+			}, nil
+		}),
+		NewStubRequest("GET", "http://example.com/empty", func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: 204}, nil
+		}),
+	)
+
+	testcases := []struct {
+		url           string
+		status        string
+		contentLength int64
+		header        string
+	}{
+		{"http://example.com/string", "201 Created", 7, "7"},
+		{"http://example.com/custom", "200 OK", -1, ""},
+		{"http://example.com/empty", "204 No Content", 0, "0"},
+	}
+
+	for _, testcase := range testcases {
+		req, _ := http.NewRequest("GET", testcase.url, nil)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.Status != testcase.status {
+			t.Errorf("%s: unexpected status, expected %s, got %s", testcase.url, testcase.status, resp.Status)
+		}
+
+		if resp.Proto != "HTTP/1.1" {
+			t.Errorf("%s: unexpected protocol, got %s", testcase.url, resp.Proto)
+		}
+
+		if resp.Request != req {
+			t.Errorf("%s: expected response to reference the originating request", testcase.url)
+		}
+
+		if resp.ContentLength != testcase.contentLength || resp.Header.Get("Content-Length") != testcase.header {
+			t.Errorf("%s: unexpected content length, got %d and %q", testcase.url, resp.ContentLength, resp.Header.Get("Content-Length"))
+		}
+
+		if _, err := http.ParseTime(resp.Header.Get("Date")); err != nil {
+			t.Errorf("%s: expected Date header, got %q", testcase.url, resp.Header.Get("Date"))
+		}
+	}
+}
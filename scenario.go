@@ -0,0 +1,139 @@
+package simular
+
+// ScenarioStarted is the state every scenario is in until a stub moves it to
+// another state.
+const ScenarioStarted = "Started"
+
+// scenarioStep holds the scenario state a stub requires in order to match,
+// and the state the scenario moves to once the stub responds.
+type scenarioStep struct {
+	name      string
+	state     string
+	nextState string
+}
+
+// WithScenario is a functional configuration option making a stubbed request
+// part of a named scenario. The stub only matches requests while the scenario
+// is in the given state, and when it responds the scenario moves to nextState.
+// An empty nextState leaves the scenario in its current state. Every scenario
+// begins in the ScenarioStarted state, for example:
+// 		simular.RegisterStubRequests(
+// 			simular.NewStubRequest(
+// 				"GET",
+// 				"https://api.mybiz.com/articles.json",
+// 				simular.NewStringResponder(200, `[]`),
+// 				simular.WithScenario("articles", simular.ScenarioStarted, ""),
+// 			),
+// 			simular.NewStubRequest(
+// 				"POST",
+// 				"https://api.mybiz.com/articles.json",
+// 				simular.NewStringResponder(201, `{"title":"article"}`),
+// 				simular.WithScenario("articles", simular.ScenarioStarted, "created"),
+// 			),
+// 			simular.NewStubRequest(
+// 				"GET",
+// 				"https://api.mybiz.com/articles.json",
+// 				simular.NewStringResponder(200, `[{"title":"article"}]`),
+// 				simular.WithScenario("articles", "created", ""),
+// 			),
+// 		)
+func WithScenario(name, state, nextState string) Option {
+	return func(r *StubRequest) {
+		r.scenario = &scenarioStep{
+			name:      name,
+			state:     state,
+			nextState: nextState,
+		}
+	}
+}
+
+// ScenarioState returns the current state of the named scenario
+func (m *MockTransport) ScenarioState(name string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.scenarioState(name)
+}
+
+// SetScenarioState moves the named scenario into the given state
+func (m *MockTransport) SetScenarioState(name, state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.setScenarioState(name, state)
+}
+
+// scenarioState returns the current state of the named scenario. The caller
+// must hold m.mu.
+func (m *MockTransport) scenarioState(name string) string {
+	if state, ok := m.scenarios[name]; ok {
+		return state
+	}
+	return ScenarioStarted
+}
+
+// setScenarioState moves the named scenario into the given state. The caller
+// must hold m.mu.
+func (m *MockTransport) setScenarioState(name, state string) {
+	if m.scenarios == nil {
+		m.scenarios = make(map[string]string)
+	}
+	m.scenarios[name] = state
+}
+
+// Scenarios returns the current state of every scenario that has left the
+// ScenarioStarted state, keyed by scenario name.
+func (m *MockTransport) Scenarios() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scenarios := make(map[string]string, len(m.scenarios))
+	for name, state := range m.scenarios {
+		scenarios[name] = state
+	}
+	return scenarios
+}
+
+// ResetScenarios returns every scenario to the ScenarioStarted state
+func (m *MockTransport) ResetScenarios() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.scenarios = nil
+}
+
+// matchScenario returns an error if the stub belongs to a scenario which is
+// not currently in the state the stub requires.
+func (m *MockTransport) matchScenario(stub *StubRequest) error {
+	if stub.scenario == nil {
+		return nil
+	}
+
+	state := m.scenarioState(stub.scenario.name)
+	if state != stub.scenario.state {
+		return &ScenarioMismatchError{Scenario: stub.scenario.name, Expected: stub.scenario.state, Actual: state}
+	}
+
+	return nil
+}
+
+// advanceScenario moves the stub's scenario into its next state, if any
+func (m *MockTransport) advanceScenario(stub *StubRequest) {
+	if stub.scenario == nil || stub.scenario.nextState == "" {
+		return
+	}
+
+	m.setScenarioState(stub.scenario.name, stub.scenario.nextState)
+}
+
+// ScenarioState returns the current state of the named scenario on the
+// default mock transport.
+func ScenarioState(name string) string {
+	return mockTransport.ScenarioState(name)
+}
+
+// SetScenarioState moves the named scenario on the default mock transport
+// into the given state.
+func SetScenarioState(name, state string) {
+	mockTransport.SetScenarioState(name, state)
+}
@@ -0,0 +1,87 @@
+package simular
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestMockTransportScenario(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest(
+			"GET",
+			testURL,
+			NewStringResponder(200, "empty"),
+			WithScenario("articles", ScenarioStarted, ""),
+		),
+		NewStubRequest(
+			"POST",
+			testURL,
+			NewStringResponder(201, "created"),
+			WithScenario("articles", ScenarioStarted, "created"),
+		),
+		NewStubRequest(
+			"GET",
+			testURL,
+			NewStringResponder(200, "list"),
+			WithScenario("articles", "created", ""),
+		),
+	)
+
+	get := func() string {
+		resp, err := client.Get(testURL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	if body := get(); body != "empty" {
+		t.Errorf("Unexpected body before creating, got %s", body)
+	}
+
+	resp, err := client.Post(testURL, "application/json", bytes.NewBufferString(`{}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if state := transport.ScenarioState("articles"); state != "created" {
+		t.Errorf("Unexpected scenario state, expected created, got %s", state)
+	}
+
+	if body := get(); body != "list" {
+		t.Errorf("Unexpected body after creating, got %s", body)
+	}
+
+	// the create stub only matches in the started state
+	_, err = client.Post(testURL, "application/json", bytes.NewBufferString(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "scenario articles: expected Started, got created") {
+		t.Errorf("Expected scenario state error, got %v", err)
+	}
+
+	transport.SetScenarioState("articles", ScenarioStarted)
+
+	if body := get(); body != "empty" {
+		t.Errorf("Unexpected body after setting state, got %s", body)
+	}
+
+	if scenarios := transport.Scenarios(); scenarios["articles"] != ScenarioStarted {
+		t.Errorf("Unexpected scenarios, got %v", scenarios)
+	}
+
+	transport.Reset()
+
+	if len(transport.Scenarios()) != 0 {
+		t.Errorf("Expected scenarios to be reset")
+	}
+}
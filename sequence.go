@@ -0,0 +1,95 @@
+package simular
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// SequenceEnd controls what a sequence responder does once every responder in
+// the sequence has been used.
+type SequenceEnd int
+
+const (
+	// RepeatLast makes the sequence keep responding with its last responder
+	RepeatLast SequenceEnd = iota
+
+	// Cycle makes the sequence start again from its first responder
+	Cycle
+
+	// FailAfterLast makes the sequence return an ErrSequenceExhausted error
+	FailAfterLast
+)
+
+// ErrSequenceExhausted is the error returned by a sequence responder
+// configured with FailAfterLast once every responder in the sequence has been
+// used.
+type ErrSequenceExhausted struct {
+	Length int
+	Calls  int
+}
+
+// Error ensures our ErrSequenceExhausted type implements the error interface
+func (e *ErrSequenceExhausted) Error() string {
+	return fmt.Sprintf("Response sequence exhausted, %d responses available, got call %d", e.Length, e.Calls)
+}
+
+// NewSequenceResponder creates a Responder that uses each of the given
+// responders in turn, one per request, behaving as configured by end once the
+// sequence is complete. This is useful for testing retry and polling logic,
+// for example:
+// 		simular.NewStubRequest(
+// 			"GET",
+// 			"https://api.mybiz.com/status",
+// 			simular.NewSequenceResponder(
+// 				simular.RepeatLast,
+// 				simular.NewStringResponder(503, "unavailable"),
+// 				simular.NewStringResponder(503, "unavailable"),
+// 				simular.NewStringResponder(200, "ok"),
+// 			),
+// 			simular.Times(3),
+// 		)
+//
+// The sequence advances every time the stub it belongs to is called, so
+// combining it with the Times option verifies that the whole sequence was
+// used.
+func NewSequenceResponder(end SequenceEnd, responders ...Responder) Responder {
+	var mu sync.Mutex
+	calls := 0
+
+	return func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		calls++
+		call := calls
+		mu.Unlock()
+
+		if len(responders) == 0 {
+			return nil, &ErrSequenceExhausted{Length: 0, Calls: call}
+		}
+
+		i := call - 1
+		if i >= len(responders) {
+			switch end {
+			case Cycle:
+				i = i % len(responders)
+			case FailAfterLast:
+				return nil, &ErrSequenceExhausted{Length: len(responders), Calls: call}
+			default:
+				i = len(responders) - 1
+			}
+		}
+
+		return responders[i](req)
+	}
+}
+
+// NewSequenceResponse creates a sequence Responder from a list of responses,
+// returning each response in turn.
+func NewSequenceResponse(end SequenceEnd, responses ...*http.Response) Responder {
+	responders := make([]Responder, 0, len(responses))
+	for _, resp := range responses {
+		responders = append(responders, ResponderFromResponse(resp))
+	}
+
+	return NewSequenceResponder(end, responders...)
+}
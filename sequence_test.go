@@ -0,0 +1,97 @@
+package simular
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNewSequenceResponder(t *testing.T) {
+	testcases := []struct {
+		label            string
+		end              SequenceEnd
+		expectedStatuses []int
+	}{
+		{
+			label:            "repeat last",
+			end:              RepeatLast,
+			expectedStatuses: []int{503, 503, 200, 200, 200},
+		},
+		{
+			label:            "cycle",
+			end:              Cycle,
+			expectedStatuses: []int{503, 503, 200, 503, 503},
+		},
+		{
+			label:            "fail after last",
+			end:              FailAfterLast,
+			expectedStatuses: []int{503, 503, 200, 0, 0},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.label, func(t *testing.T) {
+			responder := NewSequenceResponse(
+				testcase.end,
+				NewStringResponse(503, "unavailable"),
+				NewStringResponse(503, "unavailable"),
+				NewStringResponse(200, "ok"),
+			)
+
+			req, err := http.NewRequest("GET", testURL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, expected := range testcase.expectedStatuses {
+				resp, err := responder(req)
+				if expected == 0 {
+					if _, ok := err.(*ErrSequenceExhausted); !ok {
+						t.Errorf("Expected sequence exhausted error on call %d, got %v", i+1, err)
+					}
+					continue
+				}
+
+				if err != nil {
+					t.Fatalf("Unexpected error on call %d: %v", i+1, err)
+				}
+
+				if resp.StatusCode != expected {
+					t.Errorf("Unexpected status on call %d, expected %d, got %d", i+1, expected, resp.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+func TestMockTransportSequence(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest(
+			"GET",
+			testURL,
+			NewSequenceResponder(
+				FailAfterLast,
+				NewStringResponder(503, "unavailable"),
+				NewStringResponder(200, "ok"),
+			),
+			Times(2),
+		),
+	)
+
+	for _, expected := range []int{503, 200} {
+		resp, err := client.Get(testURL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != expected {
+			t.Errorf("Unexpected status, expected %d, got %d", expected, resp.StatusCode)
+		}
+	}
+
+	if err := transport.AllStubsCalled(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,151 @@
+package simular
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+)
+
+// UnmatchedHeader is the header set on responses from a server started via
+// NewServer when the request matched no stub, distinguishing them from 404
+// responses returned by stubs.
+const UnmatchedHeader = "X-Simular-Unmatched"
+
+// NewServer starts and returns a new local HTTP server whose requests are
+// served by the given transport's stubs, for testing code that doesn't use a
+// Go http.Client (subprocesses, or code dialing its own connections). See
+// Handler for how requests are matched against stubs. The caller should call
+// Close when finished, to shut it down.
+func NewServer(m *MockTransport, upstream string) *httptest.Server {
+	return httptest.NewServer(m.Handler(upstream))
+}
+
+// NewTLSServer starts and returns a new local HTTPS server whose requests are
+// served by the given transport's stubs. The server's Client method returns
+// a client configured to trust the server's certificate.
+func NewTLSServer(m *MockTransport, upstream string) *httptest.Server {
+	return httptest.NewTLSServer(m.Handler(upstream))
+}
+
+// Handler returns an http.Handler that serves requests using the transport's
+// stubs. Every request is handled exactly as though it had been sent via the
+// transport by an http.Client, so it is recorded in the journal and counts
+// towards AllStubsCalled.
+//
+// As requests arrive addressed to the local server, the path and query of each
+// request are applied to the given upstream URL (e.g.
+// https://api.example.com) to give the URL matched against the stubs. If
+// upstream is empty, the request's Host header is used instead, allowing
+// clients to address stubs for several hosts via the one server.
+//
+// Requests to paths beginning with AdminPrefix are served by the transport's
+// AdminHandler rather than being matched against stubs.
+//
+// Requests matching no stub receive a 404 response with the UnmatchedHeader
+// set, whose body describes why each stub didn't match. Any other error
+// returned by a responder, such as those injected by ConnectionReset, closes
+// the connection without a response.
+func (m *MockTransport) Handler(upstream string) http.Handler {
+	admin := m.AdminHandler()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, AdminPrefix) {
+			admin.ServeHTTP(w, r)
+			return
+		}
+
+		req, err := clientRequest(r, upstream)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp, err := m.RoundTrip(req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		writeResponse(w, resp)
+	})
+}
+
+// clientRequest converts a request received by a server into the equivalent
+// outgoing client request for the given upstream URL.
+func clientRequest(r *http.Request, upstream string) (*http.Request, error) {
+	target := &url.URL{
+		Scheme: "http",
+		Host:   r.Host,
+	}
+
+	if r.TLS != nil {
+		target.Scheme = "https"
+	}
+
+	if upstream != "" {
+		u, err := url.Parse(upstream)
+		if err != nil {
+			return nil, err
+		}
+		target = u
+	}
+
+	req := r.WithContext(r.Context())
+	req.RequestURI = ""
+	req.Host = ""
+	req.URL = &url.URL{
+		Scheme:   target.Scheme,
+		Host:     target.Host,
+		Path:     strings.TrimSuffix(target.Path, "/") + r.URL.Path,
+		RawQuery: r.URL.RawQuery,
+	}
+
+	return req, nil
+}
+
+// writeError writes the response for a request the transport returned an
+// error for.
+func writeError(w http.ResponseWriter, err error) {
+	var noResponder *ErrNoResponderFound
+	if errors.As(err, &noResponder) {
+		w.Header().Set(UnmatchedHeader, "true")
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// simulate a network failure by dropping the connection if we can
+	if hijacker, ok := w.(http.Hijacker); ok {
+		conn, _, hijackErr := hijacker.Hijack()
+		if hijackErr == nil {
+			conn.Close()
+			return
+		}
+	}
+
+	http.Error(w, fmt.Sprintf("simular: %s", err), http.StatusBadGateway)
+}
+
+// writeResponse copies a response returned by the transport to the
+// ResponseWriter, including any trailers.
+func writeResponse(w http.ResponseWriter, resp *http.Response) {
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+
+	for k := range resp.Trailer {
+		w.Header().Add("Trailer", k)
+	}
+
+	w.WriteHeader(resp.StatusCode)
+
+	io.Copy(w, resp.Body)
+
+	for k, v := range resp.Trailer {
+		w.Header()[http.CanonicalHeaderKey(k)] = v
+	}
+}
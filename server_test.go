@@ -0,0 +1,97 @@
+package simular
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewServer(t *testing.T) {
+	transport := NewMockTransport()
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", "https://api.example.com/articles?page=2", NewStringResponder(200, "articles")),
+		NewStubRequest("GET", "https://api.example.com/broken", ConnectionReset),
+	)
+
+	server := NewServer(transport, "https://api.example.com")
+	defer server.Close()
+
+	// use the server's client, as other tests replace http.DefaultTransport
+	client := server.Client()
+
+	resp, err := client.Get(server.URL + "/articles?page=2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != 200 || string(body) != "articles" {
+		t.Errorf("Unexpected response: %d %s", resp.StatusCode, body)
+	}
+
+	resp, err = client.Get(server.URL + "/unknown")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != 404 || !strings.Contains(string(body), "Unexpected URL") {
+		t.Errorf("Expected unmatched request diagnostics, got: %d %s", resp.StatusCode, body)
+	}
+
+	_, err = client.Get(server.URL + "/broken")
+	if err == nil {
+		t.Errorf("Expected connection to be dropped")
+	}
+
+	if err := transport.AllStubsCalled(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// the client may retry the request whose connection was dropped
+	journal := transport.Journal()
+	if len(journal) < 3 {
+		t.Fatalf("Expected at least 3 requests in the journal, got %d", len(journal))
+	}
+
+	if journal[0].URL.String() != "https://api.example.com/articles?page=2" {
+		t.Errorf("Unexpected URL recorded in the journal: %s", journal[0].URL)
+	}
+}
+
+func TestNewTLSServerHostHeader(t *testing.T) {
+	transport := NewMockTransport()
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", "https://api.example.com/articles", NewStringResponder(200, "articles")),
+	)
+
+	server := NewTLSServer(transport, "")
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL+"/articles", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "api.example.com"
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Unexpected status, expected 200, got %d", resp.StatusCode)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // Option is a functional configurator allowing non-standard configuration to be
@@ -27,20 +29,144 @@ func NewStubRequest(method, url string, responder Responder, options ...Option)
 		option(r)
 	}
 
+	// an invalid pattern is reported as the reason the stub doesn't match
+	r.compileURL()
+
 	return r
 }
 
 // StubRequest is used to capture data about a new stubbed request. It wraps up
 // the Method and URL along with optional http.Header struct, holds the
 // Responder used to generate a response, and also has a flag indicating
-// whether or not this stubbed request has actually been called.
+// whether or not this stubbed request has actually been called. An empty
+// Method or URL matches any method or URL, which is useful when the request is
+// instead matched by custom Matchers.
+//
+// Called is updated as requests are received, so code reading it while
+// requests may still be in flight should use CallCount instead, which is safe
+// for concurrent use.
 type StubRequest struct {
 	Method    string
 	URL       string
 	Header    *http.Header
-	Body      io.Reader
+	Body      []byte
 	Responder Responder
 	Called    bool
+
+	calls         int
+	expectedCalls *callRange
+	journal       []*JournalEntry
+	queryMatchers []queryMatcher
+	jsonBody      *jsonBody
+	bodyErr       error
+	matchers      []Matcher
+	scenario      *scenarioStep
+	pattern       *urlPattern
+	id            int
+
+	// mu guards Called, calls and journal
+	mu sync.Mutex
+}
+
+// unlimitedCalls is the maximum of a callRange with no upper bound
+const unlimitedCalls = -1
+
+// callRange holds the minimum and maximum number of times a stub expects to be
+// called. A stub with no callRange expects to be called at least once.
+type callRange struct {
+	min int
+	max int
+}
+
+// String returns a human readable description of the expected calls
+func (c *callRange) String() string {
+	switch {
+	case c.max == 0:
+		return "expected no calls"
+	case c.min == c.max:
+		return fmt.Sprintf("expected exactly %s", pluralCalls(c.min))
+	case c.max == unlimitedCalls:
+		return fmt.Sprintf("expected at least %s", pluralCalls(c.min))
+	case c.min == 0:
+		return fmt.Sprintf("expected at most %s", pluralCalls(c.max))
+	default:
+		return fmt.Sprintf("expected between %d and %s", c.min, pluralCalls(c.max))
+	}
+}
+
+// pluralCalls returns n followed by call or calls as appropriate
+func pluralCalls(n int) string {
+	if n == 1 {
+		return "1 call"
+	}
+	return fmt.Sprintf("%d calls", n)
+}
+
+// checkCalls panics if n is not a valid number of calls, as a negative number
+// would otherwise be mistaken for unlimitedCalls.
+func checkCalls(option string, n int) {
+	if n < 0 {
+		panic(fmt.Sprintf("simular: %s called with negative number of calls %d", option, n))
+	}
+}
+
+// Times is a functional configuration option used to set the exact number of
+// times a stubbed request is expected to be called. Once a stub has been
+// called this many times it stops matching requests, allowing a subsequently
+// registered stub for the same request to take over. Times panics if n is
+// negative.
+func Times(n int) Option {
+	checkCalls("Times", n)
+
+	return func(r *StubRequest) {
+		r.expectedCalls = &callRange{min: n, max: n}
+	}
+}
+
+// Once is a functional configuration option used to specify that a stubbed
+// request is expected to be called exactly once.
+func Once() Option {
+	return Times(1)
+}
+
+// AtLeast is a functional configuration option used to specify the minimum
+// number of times a stubbed request is expected to be called. AtLeast panics
+// if n is negative.
+func AtLeast(n int) Option {
+	checkCalls("AtLeast", n)
+
+	return func(r *StubRequest) {
+		r.expectedCalls = &callRange{min: n, max: unlimitedCalls}
+	}
+}
+
+// AtMost is a functional configuration option used to specify the maximum
+// number of times a stubbed request is expected to be called. Once a stub has
+// been called this many times it stops matching requests. AtMost panics if n
+// is negative.
+func AtMost(n int) Option {
+	checkCalls("AtMost", n)
+
+	return func(r *StubRequest) {
+		r.expectedCalls = &callRange{min: 0, max: n}
+	}
+}
+
+// Never is a functional configuration option used to specify that a stubbed
+// request should never be called. Any matching request will fail, and will be
+// reported by AllStubsCalled.
+func Never() Option {
+	return Times(0)
+}
+
+// WithMatcher is a functional configuration option used to add custom
+// Matchers to a stubbed request. A request only matches the stub if every
+// matcher returns nil. Matchers are run after the built in method, URL, header
+// and body checks.
+func WithMatcher(matchers ...Matcher) Option {
+	return func(r *StubRequest) {
+		r.matchers = append(r.matchers, matchers...)
+	}
 }
 
 // WithHeader is a functional configuration option used to add http headers onto
@@ -52,66 +178,267 @@ func WithHeader(header *http.Header) Option {
 }
 
 // WithBody is a functional configuration option used to add a body to a stubbed
-// request
+// request. The reader is read in full when the option is applied, so the
+// stub can be compared against any number of requests.
 func WithBody(body io.Reader) Option {
 	return func(r *StubRequest) {
-		r.Body = body
+		r.Body, r.bodyErr = ioutil.ReadAll(body)
 	}
 }
 
 // Matches is a function that returns true if an incoming request is matched by
 // this fetcher. Should an incoming request URL cause an error when normalized,
-// we return false.
+// we return false. Any request body read while matching is restored, so the
+// request can be passed on to other stubs.
 func (r *StubRequest) Matches(req *http.Request) error {
-	if !strings.EqualFold(req.Method, r.Method) {
-		return fmt.Errorf("Unexpected method, expected %s, got %s", r.Method, req.Method)
+	if errs := r.mismatches(req, false); len(errs) > 0 {
+		return errs[0]
 	}
 
-	normalizedURL, err := normalizeURL(r.URL)
-	if err != nil {
-		return err
+	return nil
+}
+
+// mismatches checks the request against each requirement of the stub,
+// returning a MismatchError for every requirement the request doesn't
+// satisfy. Unless all is true we stop at the first mismatch.
+func (r *StubRequest) mismatches(req *http.Request, all bool) []error {
+	var errs []error
+
+	// mismatch records the error, returning true if we should stop checking
+	mismatch := func(err error) bool {
+		errs = append(errs, err)
+		return !all
 	}
 
-	normalizedReqURL, err := normalizeURL(req.URL.String())
-	if err != nil {
-		return err
+	if r.Method != "" && !strings.EqualFold(req.Method, r.Method) {
+		if mismatch(mismatchf(MismatchMethod, "", r.Method, req.Method, "Unexpected method, expected %s, got %s", r.Method, req.Method)) {
+			return errs
+		}
 	}
 
-	if normalizedURL != normalizedReqURL {
-		return fmt.Errorf("Unexpected URL, expected %s, got %s", normalizedURL, normalizedReqURL)
+	if err := r.matchURL(req); err != nil {
+		if _, ok := err.(*MismatchError); !ok {
+			err = &MismatchError{Kind: MismatchURL, Err: err}
+		}
+		if mismatch(err) {
+			return errs
+		}
 	}
 
 	// only check headers if the stubbed request has set headers to some not nil
 	// value
 	if r.Header != nil {
+		names := make([]string, 0, len(*r.Header))
+		for name := range *r.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
 		// for each header defined on the stub, iterate through all the values and
 		// make sure they are present in the corresponding header on the request
-		for header, stubValues := range map[string][]string(*r.Header) {
+		for _, header := range names {
+			stubValues := (*r.Header)[header]
+
 			// get the values for this header on the request
 			reqValues := req.Header[http.CanonicalHeaderKey(header)]
 			for _, v := range stubValues {
-				if !contains(reqValues, v) {
-					return fmt.Errorf("Unexpected request headers, expected: %v, got %v", r.Header, req.Header)
+				if contains(reqValues, v) {
+					continue
 				}
+
+				err := mismatchf(
+					MismatchHeader,
+					http.CanonicalHeaderKey(header),
+					strings.Join(stubValues, ", "),
+					strings.Join(reqValues, ", "),
+					"Unexpected request headers, expected: %v, got %v", r.Header, req.Header,
+				)
+				if mismatch(err) {
+					return errs
+				}
+				break
 			}
 		}
 	}
 
+	if r.Body == nil && r.jsonBody == nil && len(r.matchers) == 0 {
+		return errs
+	}
+
+	if r.bodyErr != nil {
+		return append(errs, &MismatchError{Kind: MismatchBody, Err: r.bodyErr})
+	}
+
+	requestBody, err := readBody(req)
+	if err != nil {
+		return append(errs, &MismatchError{Kind: MismatchBody, Err: err})
+	}
+	defer resetBody(req, requestBody)
+
 	// if our stub includes a body, then it should equal the actual request body
 	// to match
 	if r.Body != nil {
-		stubBody, err := ioutil.ReadAll(r.Body)
+		if bytes.Compare(r.Body, requestBody) != 0 {
+			if mismatch(mismatchf(MismatchBody, "", string(r.Body), string(requestBody), "Unexpected request body, expected %s, got %s", r.Body, requestBody)) {
+				return errs
+			}
+		}
+	}
+
+	// if our stub includes a JSON body, then it should be structurally equal to
+	// the request body
+	if r.jsonBody != nil {
+		if err := r.jsonBody.match(requestBody); err != nil {
+			if _, ok := err.(*MismatchError); !ok {
+				err = &MismatchError{Kind: MismatchBody, Err: err}
+			}
+			if mismatch(err) {
+				return errs
+			}
+		}
+	}
+
+	// finally run any custom matchers, each with a fresh copy of the body
+	for _, matcher := range r.matchers {
+		resetBody(req, requestBody)
+
+		if err := matcher.Match(req); err != nil {
+			if _, ok := err.(*MismatchError); !ok {
+				err = &MismatchError{Kind: MismatchCustom, Err: err}
+			}
+			if mismatch(err) {
+				return errs
+			}
+		}
+	}
+
+	return errs
+}
+
+// CallCount returns the number of times this stubbed request has been called,
+// including any calls made after the stub's expected calls were exhausted.
+func (r *StubRequest) CallCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.calls
+}
+
+// Journal returns an entry for every request this stubbed request responded
+// to, in the order they were received. As with MockTransport.Journal, the
+// entries are copies.
+func (r *StubRequest) Journal() []*JournalEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return copyJournal(r.journal)
+}
+
+// recordCall marks this stub as having been called
+func (r *StubRequest) recordCall() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Called = true
+	r.calls++
+}
+
+// recordEntry adds an entry to this stub's journal
+func (r *StubRequest) recordEntry(entry *JournalEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.journal = append(r.journal, entry)
+}
+
+// exhausted returns true if the stub has already been called the maximum
+// number of times it expects, so should no longer respond to requests.
+func (r *StubRequest) exhausted() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.expectedCalls == nil || r.expectedCalls.max == unlimitedCalls {
+		return false
+	}
+	return r.calls >= r.expectedCalls.max
+}
+
+// verifyCalls returns an error if the number of times this stub was called
+// doesn't satisfy its expected calls.
+func (r *StubRequest) verifyCalls() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.expectedCalls == nil {
+		if r.calls == 0 {
+			return fmt.Errorf("expected at least 1 call, got 0")
+		}
+		return nil
+	}
+
+	if r.calls < r.expectedCalls.min ||
+		(r.expectedCalls.max != unlimitedCalls && r.calls > r.expectedCalls.max) {
+		return fmt.Errorf("%s, got %d", r.expectedCalls, r.calls)
+	}
+
+	return nil
+}
+
+// matchURL checks the URL of the incoming request. If the stub has query
+// options configured, then the query string is excluded from the comparison
+// and checked by those options instead.
+func (r *StubRequest) matchURL(req *http.Request) error {
+	if r.URL != "" && isURLPattern(r.URL) {
+		if _, err := r.matchURLPattern(req); err != nil {
+			return err
+		}
+	}
+
+	if len(r.queryMatchers) == 0 {
+		if r.URL == "" || isURLPattern(r.URL) {
+			return nil
+		}
+
+		normalizedURL, err := normalizeURL(r.URL)
+		if err != nil {
+			return err
+		}
+
+		normalizedReqURL, err := normalizeURL(req.URL.String())
 		if err != nil {
 			return err
 		}
 
-		requestBody, err := ioutil.ReadAll(req.Body)
+		if normalizedURL != normalizedReqURL {
+			return mismatchf(MismatchURL, "", normalizedURL, normalizedReqURL, "Unexpected URL, expected %s, got %s", normalizedURL, normalizedReqURL)
+		}
+
+		return nil
+	}
+
+	normalizedReqURL, reqQuery, err := splitQuery(req.URL.String())
+	if err != nil {
+		return err
+	}
+
+	if r.URL != "" && !isURLPattern(r.URL) {
+		normalizedURL, stubQuery, err := splitQuery(r.URL)
 		if err != nil {
 			return err
 		}
 
-		if bytes.Compare(stubBody, requestBody) != 0 {
-			return fmt.Errorf("Unexpected request body, expected %s, got %s", stubBody, requestBody)
+		if normalizedURL != normalizedReqURL {
+			return mismatchf(MismatchURL, "", normalizedURL, normalizedReqURL, "Unexpected URL, expected %s, got %s", normalizedURL, normalizedReqURL)
+		}
+
+		if err := requireQueryValues(stubQuery, reqQuery, false); err != nil {
+			return err
+		}
+	}
+
+	for _, matcher := range r.queryMatchers {
+		if err := matcher(reqQuery); err != nil {
+			return err
 		}
 	}
 
@@ -256,3 +256,125 @@ func TestStubbedRequestStringer(t *testing.T) {
 		}
 	}
 }
+
+func TestVerifyCalls(t *testing.T) {
+	testcases := []struct {
+		label       string
+		options     []Option
+		calls       int
+		exhausted   bool
+		expectedErr string
+	}{
+		{
+			label:       "default uncalled",
+			calls:       0,
+			expectedErr: "expected at least 1 call, got 0",
+		},
+		{
+			label: "default called many times",
+			calls: 5,
+		},
+		{
+			label:     "times satisfied",
+			options:   []Option{Times(2)},
+			calls:     2,
+			exhausted: true,
+		},
+		{
+			label:       "once called too often",
+			options:     []Option{Once()},
+			calls:       5,
+			exhausted:   true,
+			expectedErr: "expected exactly 1 call, got 5",
+		},
+		{
+			label:       "at least not satisfied",
+			options:     []Option{AtLeast(3)},
+			calls:       2,
+			expectedErr: "expected at least 3 calls, got 2",
+		},
+		{
+			label:   "at most uncalled",
+			options: []Option{AtMost(2)},
+			calls:   0,
+		},
+		{
+			label:       "at most called too often",
+			options:     []Option{AtMost(1)},
+			calls:       2,
+			exhausted:   true,
+			expectedErr: "expected at most 1 call, got 2",
+		},
+		{
+			label:       "never called",
+			options:     []Option{Never()},
+			calls:       1,
+			exhausted:   true,
+			expectedErr: "expected no calls, got 1",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.label, func(t *testing.T) {
+			stub := NewStubRequest("GET", "http://example.com", NewStringResponder(200, "ok"), testcase.options...)
+
+			for i := 0; i < testcase.calls; i++ {
+				stub.recordCall()
+			}
+
+			if stub.CallCount() != testcase.calls {
+				t.Errorf("Unexpected call count, expected %d, got %d", testcase.calls, stub.CallCount())
+			}
+
+			if stub.exhausted() != testcase.exhausted {
+				t.Errorf("Unexpected exhausted state, expected %v", testcase.exhausted)
+			}
+
+			err := stub.verifyCalls()
+			if testcase.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error, got %s", err)
+				}
+			} else {
+				if err == nil || err.Error() != testcase.expectedErr {
+					t.Errorf("Unexpected error, expected '%s', got '%v'", testcase.expectedErr, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNegativeCallsPanic(t *testing.T) {
+	testcases := map[string]func(){
+		"Times":   func() { Times(-1) },
+		"AtLeast": func() { AtLeast(-1) },
+		"AtMost":  func() { AtMost(-1) },
+	}
+
+	for label, option := range testcases {
+		t.Run(label, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected %s to panic with negative number of calls", label)
+				}
+			}()
+
+			option()
+		})
+	}
+}
+
+func TestMatchesRestoresBody(t *testing.T) {
+	stub := NewStubRequest("POST", "http://example.com", NewStringResponder(200, "ok"), WithBody(bytes.NewBufferString("foo=val")))
+
+	req, err := http.NewRequest("POST", "http://example.com", bytes.NewBufferString("foo=val"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := stub.Matches(req); err != nil {
+			t.Errorf("Unexpected error on attempt %d, got %s", i, err)
+		}
+	}
+}
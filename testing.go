@@ -0,0 +1,64 @@
+package simular
+
+import (
+	"net/http"
+	"testing"
+)
+
+// ActivateT activates the default mock environment for the duration of the
+// given test. It registers a cleanup function with t that fails the test if
+// not all registered stubs were called or if any request matched no stub, and
+// then deactivates and resets the mock environment. This replaces the usual
+// Activate, defer DeactivateAndReset and AllStubsCalled boilerplate:
+// 		func TestFetchArticles(t *testing.T) {
+// 			simular.ActivateT(t)
+//
+// 			simular.RegisterStubRequests(...)
+//
+// 			// do stuff that makes requests
+// 		}
+func ActivateT(t testing.TB, opts ...func()) {
+	t.Helper()
+
+	if Disabled() {
+		return
+	}
+
+	Activate(opts...)
+
+	t.Cleanup(func() {
+		mockTransport.verify(t)
+		DeactivateAndReset()
+	})
+}
+
+// NewMockClientT is the testing equivalent of NewMockClient. The returned
+// transport is verified when the test completes, failing the test if not all
+// registered stubs were called or if any request matched no stub. As the
+// transport is isolated, this is safe to use from parallel tests.
+func NewMockClientT(t testing.TB, opts ...TransportOption) (*MockTransport, *http.Client) {
+	t.Helper()
+
+	m, client := NewMockClient(opts...)
+
+	t.Cleanup(func() {
+		m.verify(t)
+		m.Reset()
+	})
+
+	return m, client
+}
+
+// verify reports any uncalled stubs and unmatched requests as errors on the
+// given test.
+func (m *MockTransport) verify(t testing.TB) {
+	t.Helper()
+
+	if err := m.AllStubsCalled(); err != nil {
+		t.Errorf("simular: %s", err)
+	}
+
+	for _, req := range m.UnmatchedRequests() {
+		t.Errorf("simular: unmatched request %s %s", req.Method, req.URL)
+	}
+}
@@ -0,0 +1,106 @@
+package simular
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeT wraps a testing.TB capturing any reported errors and cleanup
+// functions, so we can verify the behaviour of our testing helpers.
+type fakeT struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *fakeT) runCleanups() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestActivateT(t *testing.T) {
+	DeactivateAndReset()
+
+	ft := &fakeT{TB: t}
+	ActivateT(ft)
+
+	if http.DefaultTransport != mockTransport {
+		t.Fatal("expected http.DefaultTransport to be our mockTransport")
+	}
+
+	RegisterStubRequests(
+		NewStubRequest("GET", "http://example.com", NewStringResponder(200, "ok")),
+		NewStubRequest("GET", "http://github.com", NewStringResponder(200, "ok")),
+	)
+
+	resp, err := http.Get("http://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	_, err = http.Get("http://unknown.com/path")
+	if err == nil {
+		t.Fatal("expected error requesting unknown url")
+	}
+
+	ft.runCleanups()
+
+	if http.DefaultTransport == mockTransport {
+		t.Error("expected mock environment to have been deactivated")
+	}
+
+	if len(mockTransport.stubs) != 0 {
+		t.Error("expected mock environment to have been reset")
+	}
+
+	if len(ft.errors) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(ft.errors), ft.errors)
+	}
+
+	if !strings.Contains(ft.errors[0], "http://github.com") {
+		t.Errorf("expected uncalled stub error, got '%s'", ft.errors[0])
+	}
+
+	if !strings.Contains(ft.errors[1], "GET http://unknown.com/path") {
+		t.Errorf("expected unmatched request error, got '%s'", ft.errors[1])
+	}
+}
+
+func TestNewMockClientT(t *testing.T) {
+	ft := &fakeT{TB: t}
+	transport, client := NewMockClientT(ft)
+
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", testURL, NewStringResponder(200, "ok")),
+	)
+
+	resp, err := client.Get(testURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	ft.runCleanups()
+
+	if len(ft.errors) != 0 {
+		t.Errorf("expected no errors, got %v", ft.errors)
+	}
+
+	if len(transport.stubs) != 0 {
+		t.Error("expected transport to have been reset")
+	}
+}
@@ -1,6 +1,9 @@
 package simular
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"sync"
@@ -20,100 +23,492 @@ func ConnectionFailure(req *http.Request, err error) (*http.Response, error) {
 	return nil, err
 }
 
+// TransportOption is a functional configurator used to configure a
+// MockTransport created via NewMockTransport or NewMockClient.
+type TransportOption func(*MockTransport)
+
+// AllowHosts is a TransportOption that configures a list of hosts to which
+// requests are permitted to pass through to the real network. A list of
+// hostnames excluding any scheme or port parts can be passed here. This is the
+// per transport equivalent of WithAllowedHosts.
+func AllowHosts(hosts ...string) TransportOption {
+	return func(m *MockTransport) {
+		m.allowedHosts = append(m.allowedHosts, hosts...)
+	}
+}
+
+// WithNoResponder is a TransportOption that sets the responder called when no
+// stubbed request matches an incoming request. The default is
+// ConnectionFailure.
+func WithNoResponder(responder Responder) TransportOption {
+	return func(m *MockTransport) {
+		m.noResponder = responder
+	}
+}
+
+// WithPassthrough is a TransportOption that sets the http.RoundTripper used to
+// make real requests to allowed hosts. If not set, the transport that was in
+// place before Activate was called is used.
+func WithPassthrough(transport http.RoundTripper) TransportOption {
+	return func(m *MockTransport) {
+		m.passthrough = transport
+	}
+}
+
+// WithRecorder is a TransportOption that puts the transport into recording
+// mode. Any request that matches no stub is forwarded to the real network via
+// the passthrough transport, and the request and response are recorded onto
+// the given Cassette.
+func WithRecorder(cassette *Cassette) TransportOption {
+	return func(m *MockTransport) {
+		m.recorder = cassette
+	}
+}
+
 // NewMockTransport creates a new *MockTransport with no stubbed requests.
-func NewMockTransport() *MockTransport {
-	return &MockTransport{
+func NewMockTransport(opts ...TransportOption) *MockTransport {
+	m := &MockTransport{
 		stubs:       make([]*StubRequest, 0),
 		noResponder: nil,
 	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// NewMockClient returns a new isolated *MockTransport along with an
+// *http.Client that sends all its requests via that transport. Unlike
+// Activate this never touches http.DefaultTransport or any other package level
+// state, so each test (including tests calling t.Parallel()) can have its own
+// mock environment:
+// 		func TestFetchArticles(t *testing.T) {
+// 			t.Parallel()
+//
+// 			transport, client := simular.NewMockClient()
+// 			transport.RegisterStubRequests(...)
+//
+// 			// pass client to the code under test
+// 		}
+func NewMockClient(opts ...TransportOption) (*MockTransport, *http.Client) {
+	m := NewMockTransport(opts...)
+
+	return m, &http.Client{Transport: m}
 }
 
 // MockTransport implements http.RoundTripper, which fulfills single http requests issued by
 // an http.Client.  This implementation doesn't actually make the call, instead deferring to
 // the registered list of stubbed requests.
+//
+// A MockTransport is safe for concurrent use by multiple goroutines, so may be
+// shared by clients making requests in parallel while stubs are registered,
+// removed and inspected. Responders are called without any lock held, but
+// Matchers are called while the transport is locked, so must not call methods
+// on the transport.
 type MockTransport struct {
-	stubs       []*StubRequest
-	noResponder Responder
-	mu          sync.Mutex
+	stubs        []*StubRequest
+	noResponder  Responder
+	allowedHosts []string
+	passthrough  http.RoundTripper
+	unmatched    []*http.Request
+	journal      []*JournalEntry
+	recorder     *Cassette
+	scenarios    map[string]string
+	inflight     map[*http.Request]context.CancelFunc
+	chaos        *chaosMonkey
+	lastID       int
+	mu           sync.Mutex
 }
 
 // RoundTrip receives HTTP requests and routes them to the appropriate responder.  It is required to
 // implement the http.RoundTripper interface.  You will not interact with this directly, instead
 // the *http.Client you are using will call it for you.
+//
+// Any fields of the response a real transport would set but the Responder
+// did not, such as Status, Proto, ContentLength, Request and the Date header,
+// are filled in.
 func (m *MockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	orig := req
+
+	// make the request cancellable via CancelRequest. As with a real
+	// transport, the request stays in flight until the response body is
+	// closed, so bodies still streaming from the network can be read, and
+	// reading them can be cancelled.
+	ctx, cancel := context.WithCancel(req.Context())
+	finish := m.trackRequest(req, cancel)
+
+	req, entry, err := newJournalEntry(req.WithContext(ctx))
+	if err != nil {
+		finish()
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.journal = append(m.journal, entry)
+	m.mu.Unlock()
+
+	resp, err := m.respond(req, entry)
+	if resp != nil {
+		resp = populateResponse(orig, resp)
+	}
+	m.completeEntry(entry, resp, err)
+
+	if resp == nil {
+		finish()
+		return resp, err
+	}
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, finish: finish}
+
+	return resp, err
+}
+
+// completeEntry records the outcome of a request on its journal entry. The
+// entry may be shared by the transport's and the stub's journals, so we lock
+// both.
+func (m *MockTransport) completeEntry(entry *JournalEntry, resp *http.Response, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry.Stub != nil {
+		entry.Stub.mu.Lock()
+		defer entry.Stub.mu.Unlock()
+	}
+
+	entry.complete(resp, err)
+}
+
+// respond finds the stub matching the given request and generates a response
+// from it, recording the matched stub in the journal entry. The transport is
+// locked while the stub is chosen and its call recorded, so concurrent
+// requests can't both claim a stub's final expected call, but is unlocked
+// before the response is generated.
+func (m *MockTransport) respond(req *http.Request, entry *JournalEntry) (*http.Response, error) {
+	m.mu.Lock()
+
 	// try and get a responder that matches the given request
-	stub, err := m.stubForRequest(req)
+	stub, err := m.stubForRequest(req, entry.Body)
+
+	// give whatever handles the request a fresh copy of the body
+	resetBody(req, entry.Body)
 
 	// we didn't find a responder so fire the 'no responder' responder
 	if err != nil {
-		// check if this is an allowed request - if so make the request
-		if isAllowed(req) {
-			return initialTransport.RoundTrip(req)
+		allowed, recorder, noResponder := m.isAllowed(req), m.recorder, m.noResponder
+		if !allowed && recorder == nil && noResponder == nil {
+			m.unmatched = append(m.unmatched, req)
 		}
-
-		if m.noResponder == nil {
+		m.mu.Unlock()
+
+		switch {
+		case allowed:
+			// this is an allowed request, so make the request
+			return m.passthroughTransport().RoundTrip(req)
+		case recorder != nil:
+			// we are recording, so forward the request and record the response
+			return recorder.record(m.passthroughTransport(), req, entry.Body)
+		case noResponder == nil:
+			// the error is returned to the client, so describe the closest stubs
+			if noMatch, ok := err.(*ErrNoResponderFound); ok {
+				noMatch.rankCandidates()
+			}
 			return ConnectionFailure(req, err)
 		}
-		return m.noResponder(req)
+		return noResponder(req)
 	}
 
 	// mark this stub as having been performed
-	stub.Called = true
+	stub.recordCall()
+	m.advanceScenario(stub)
+
+	entry.Stub = stub
+	stub.recordEntry(entry)
+
+	chaos := m.chaos
+	m.mu.Unlock()
+
+	// make any parameters captured from the URL available to the responder
+	req = withPathParams(req, stub.pathParams(req))
+
+	resp, err := stub.Responder(req)
+	if err != nil || chaos == nil {
+		return resp, err
+	}
+
+	return chaos.apply(req, resp)
+}
+
+// passthroughTransport returns the http.RoundTripper used for requests to
+// allowed hosts.
+func (m *MockTransport) passthroughTransport() http.RoundTripper {
+	if m.passthrough != nil {
+		return m.passthrough
+	}
+	return initialTransport
+}
+
+// CancelRequest cancels an in flight request by cancelling the context of
+// the request passed to the responder. Responders which honor the request's
+// context, such as those created by NewDelayedResponder, then return
+// promptly with context.Canceled.
+func (m *MockTransport) CancelRequest(req *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	return stub.Responder(req)
+	if cancel, ok := m.inflight[req]; ok {
+		cancel()
+	}
 }
 
-// CancelRequest does nothing with timeout
-func (m *MockTransport) CancelRequest(req *http.Request) {}
+// trackRequest records the cancel function for an in flight request,
+// returning a function to call once the request has completed.
+func (m *MockTransport) trackRequest(req *http.Request, cancel context.CancelFunc) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.inflight == nil {
+		m.inflight = make(map[*http.Request]context.CancelFunc)
+	}
+	m.inflight[req] = cancel
+
+	return func() {
+		m.untrackRequest(req)
+		cancel()
+	}
+}
+
+// untrackRequest forgets a request once it has completed
+func (m *MockTransport) untrackRequest(req *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.inflight, req)
+}
+
+// cancelBody is a response body which completes its request when closed
+type cancelBody struct {
+	io.ReadCloser
+	finish func()
+}
+
+// Close closes the underlying body, then forgets and cancels the request
+func (b *cancelBody) Close() error {
+	defer b.finish()
+	return b.ReadCloser.Close()
+}
 
 // stubForRequest returns the first matching stub for the incoming request
-// object or nil if no stub claims to be a match
-func (m *MockTransport) stubForRequest(req *http.Request) (*StubRequest, error) {
-	var err error
+// object or nil if no stub claims to be a match. Each stub is given a fresh
+// copy of the buffered request body. Stubs that have already been
+// called as many times as they expect are skipped, but if no other stub
+// matches, the call is recorded against the first exhausted stub so that
+// AllStubsCalled can report it. The caller must hold m.mu.
+func (m *MockTransport) stubForRequest(req *http.Request, body []byte) (*StubRequest, error) {
 	var errs = []error{}
+	var rejections = []*rejection{}
+	var exhausted *StubRequest
 
-	// find the first stub that matches the request
+	// find the first stub that matches the request, collecting every reason
+	// the others don't so they can be ranked if no stub matches
 	for _, stub := range m.stubs {
-		err = stub.Matches(req)
-		if err == nil {
-			return stub, nil
+		resetBody(req, body)
+
+		var mismatches []error
+		if err := m.matchScenario(stub); err != nil {
+			mismatches = append(mismatches, err)
 		}
-		errs = append(errs, err)
+		mismatches = append(mismatches, stub.mismatches(req, true)...)
+
+		if len(mismatches) == 0 {
+			if !stub.exhausted() {
+				return stub, nil
+			}
+
+			if exhausted == nil {
+				exhausted = stub
+			}
+			mismatches = append(mismatches, &ExhaustedError{Stub: stub, Calls: stub.CallCount()})
+		}
+
+		errs = append(errs, mismatches[0])
+		rejections = append(rejections, &rejection{stub: stub, errs: mismatches})
+	}
+
+	noMatch := NewErrNoResponderFound(errs)
+	noMatch.request = fmt.Sprintf("%s %s", req.Method, req.URL)
+	noMatch.url = cloneURL(req.URL)
+	noMatch.rejections = rejections
+
+	if exhausted != nil {
+		exhausted.recordCall()
 	}
 
-	return nil, NewErrNoResponderFound(errs)
+	return nil, noMatch
 }
 
 // RegisterStubRequests adds multiple stub requests with associated responders.
 // When a request comes in that matches, the appropriate responder will be
 // called and the response returned to the client.
 func (m *MockTransport) RegisterStubRequests(stubs ...*StubRequest) {
+	// an invalid URL pattern is reported as the reason the stub doesn't match
+	for _, stub := range stubs {
+		stub.compileURL()
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	for _, stub := range stubs {
+		if stub.id == 0 {
+			m.lastID++
+			stub.id = m.lastID
+		}
+	}
+
 	m.stubs = append(m.stubs, stubs...)
 }
 
+// StubRequests returns the stubbed requests currently registered on the
+// transport, in the order they are matched.
+func (m *MockTransport) StubRequests() []*StubRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*StubRequest(nil), m.stubs...)
+}
+
+// ReplaceStubRequests atomically replaces the given previously registered
+// stubs with new ones, so no request is matched against a partial set of
+// stubs. The new stubs take the place of the first old stub still registered,
+// or are added after every other stub if none are. Any other stubs, the
+// journal and the state of scenarios are left untouched.
+func (m *MockTransport) ReplaceStubRequests(old []*StubRequest, stubs ...*StubRequest) {
+	for _, stub := range stubs {
+		stub.compileURL()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, stub := range stubs {
+		if stub.id == 0 {
+			m.lastID++
+			stub.id = m.lastID
+		}
+	}
+
+	replaced := make(map[*StubRequest]bool, len(old))
+	for _, stub := range old {
+		replaced[stub] = true
+	}
+
+	remaining := make([]*StubRequest, 0, len(m.stubs)+len(stubs))
+	inserted := false
+
+	for _, stub := range m.stubs {
+		if !replaced[stub] {
+			remaining = append(remaining, stub)
+			continue
+		}
+
+		if !inserted {
+			remaining = append(remaining, stubs...)
+			inserted = true
+		}
+	}
+
+	if !inserted {
+		remaining = append(remaining, stubs...)
+	}
+
+	m.stubs = remaining
+}
+
+// RemoveStubRequest removes a previously registered stubbed request from the
+// transport, returning false if the stub was not registered.
+func (m *MockTransport) RemoveStubRequest(stub *StubRequest) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, s := range m.stubs {
+		if s == stub {
+			m.stubs = append(m.stubs[:i:i], m.stubs[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
 // RegisterNoResponder is used to register a responder that will be called if
 // no other responder is found.  The default is ConnectionFailure.
 func (m *MockTransport) RegisterNoResponder(responder Responder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.noResponder = responder
 }
 
-// Reset removes all registered responders (including the no responder) from
-// the MockTransport
+// Reset removes all registered responders (including the no responder and any
+// recorder) from the MockTransport, clears the journal of received requests
+// and returns every scenario to its initial state.
 func (m *MockTransport) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.stubs = make([]*StubRequest, 0)
 	m.noResponder = nil
+	m.unmatched = nil
+	m.journal = nil
+	m.recorder = nil
+	m.scenarios = nil
+}
+
+// Record puts the transport into recording mode, recording any request that
+// matches no stub onto the given Cassette after forwarding it to the real
+// network. Passing nil stops recording.
+func (m *MockTransport) Record(cassette *Cassette) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.recorder = cassette
+}
+
+// Journal returns an entry for every request received by the transport since
+// it was created or last reset, in the order they were received. The entries
+// are copies, so the outcome of a request still in flight is not updated once
+// it completes.
+func (m *MockTransport) Journal() []*JournalEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return copyJournal(m.journal)
+}
+
+// UnmatchedRequests returns the requests received by the transport that
+// matched no stub and were rejected with a connection failure. Requests
+// handled by a registered no responder or sent to an allowed host are not
+// included.
+func (m *MockTransport) UnmatchedRequests() []*http.Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*http.Request(nil), m.unmatched...)
 }
 
 // AllStubsCalled returns nil if all of the currently registered stubs have
-// been called; if some haven't been called, then it returns an error.
+// been called the expected number of times; if some haven't, then it returns
+// an error.
 func (m *MockTransport) AllStubsCalled() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	var uncalledStubs []*StubRequest
 
 	for _, stub := range m.stubs {
-		if stub.Called == false {
+		if stub.verifyCalls() != nil {
 			uncalledStubs = append(uncalledStubs, stub)
 		}
 	}
@@ -138,20 +533,16 @@ var initialTransport = http.DefaultTransport
 var oldTransport http.RoundTripper
 var oldClient *http.Client
 
-// allowedHosts is a string slice used to hold a list of allowed hosts. An
-// allowed host is one we permit outgoing requests to even while the mocks are
-// activated.
-var allowedHosts []string
-
 // WithAllowedHosts is used to configure the behaviour of simular, by allowing
 // clients to specify a list of allowed hosts when calling Activate. A list of
 // hostnames excluding any scheme or port parts can be passed here, and any
 // requests matching that hostname will be allowed to proceed as normal.
 func WithAllowedHosts(hosts ...string) func() {
 	return func() {
-		for _, host := range hosts {
-			allowedHosts = append(allowedHosts, host)
-		}
+		mockTransport.mu.Lock()
+		defer mockTransport.mu.Unlock()
+
+		AllowHosts(hosts...)(mockTransport)
 	}
 }
 
@@ -187,7 +578,9 @@ func Activate(opts ...func()) {
 	http.DefaultTransport = mockTransport
 
 	// make sure to reset allowedHosts here
-	allowedHosts = []string{}
+	mockTransport.mu.Lock()
+	mockTransport.allowedHosts = []string{}
+	mockTransport.mu.Unlock()
 
 	// invoke our configuration option functions
 	for _, opt := range opts {
@@ -277,6 +670,29 @@ func RegisterNoResponder(responder Responder) {
 	mockTransport.RegisterNoResponder(responder)
 }
 
+// Record puts the default mock transport into recording mode. Any request
+// that matches no registered stub is made for real, and the request and
+// response are recorded onto the given Cassette. The cassette can then be
+// saved, and later loaded and replayed:
+// 		func TestFetchArticles(t *testing.T) {
+// 			simular.Activate()
+// 			defer simular.DeactivateAndReset()
+//
+// 			cassette, err := simular.LoadCassette("fixtures/articles.json")
+// 			if err != nil {
+// 				cassette = simular.NewCassette()
+// 				simular.Record(cassette)
+// 				defer cassette.Save("fixtures/articles.json")
+// 			} else {
+// 				simular.RegisterStubRequests(cassette.StubRequests()...)
+// 			}
+//
+// 			// do stuff that makes requests
+// 		}
+func Record(cassette *Cassette) {
+	mockTransport.Record(cassette)
+}
+
 // AllStubsCalled is a function intended to be used within your tests to
 // verify that all registered stubs were actually invoked during the course of
 // the test. Registering stubs but then not calling them is either a sign that
@@ -287,10 +703,16 @@ func AllStubsCalled() error {
 	return mockTransport.AllStubsCalled()
 }
 
-// isAllowed checks a request against a list of allowed hosts. If the hostname
-// matches, then we permit the outgoing request.
-func isAllowed(req *http.Request) bool {
-	for _, host := range allowedHosts {
+// Journal returns the journal of requests received by the default mock
+// transport.
+func Journal() []*JournalEntry {
+	return mockTransport.Journal()
+}
+
+// isAllowed checks a request against the transport's list of allowed hosts.
+// If the hostname matches, then we permit the outgoing request.
+func (m *MockTransport) isAllowed(req *http.Request) bool {
+	for _, host := range m.allowedHosts {
 		if stripPort(req.URL.Host) == host {
 			return true
 		}
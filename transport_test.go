@@ -7,7 +7,9 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -270,7 +272,7 @@ func TestMockTransportNoResponder(t *testing.T) {
 		t.Fatal("expected to receive a connection error due to lack of responders")
 	}
 
-	if err.Error() != "Get http://www.example.com/: No responders found" {
+	if err.Error() != `Get "http://www.example.com/": No responders found` {
 		t.Errorf("Unexpected error: %s", err.Error())
 	}
 
@@ -460,3 +462,300 @@ func TestMockTransportNonDefaultAllowedHosts(t *testing.T) {
 	// restore our original
 	initialTransport = cachedTransport
 }
+
+func TestNewMockClient(t *testing.T) {
+	testcases := []struct {
+		label string
+		url   string
+		body  string
+	}{
+		{
+			"first client",
+			"http://www.example.com/first",
+			"first",
+		},
+		{
+			"second client",
+			"http://www.example.com/second",
+			"second",
+		},
+	}
+
+	for _, testcase := range testcases {
+		testcase := testcase
+
+		t.Run(testcase.label, func(t *testing.T) {
+			t.Parallel()
+
+			transport, client := NewMockClient()
+
+			transport.RegisterStubRequests(
+				NewStubRequest("GET", testcase.url, NewStringResponder(200, testcase.body)),
+			)
+
+			resp, err := client.Get(testcase.url)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+
+			data, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != testcase.body {
+				t.Errorf("Unexpected body, expected %s, got %s", testcase.body, data)
+			}
+
+			if err := transport.AllStubsCalled(); err != nil {
+				t.Errorf("Not all stubs were called: %s", err)
+			}
+
+			if http.DefaultTransport == transport {
+				t.Errorf("Expected http.DefaultTransport to be untouched")
+			}
+		})
+	}
+}
+
+func TestNewMockClientOptions(t *testing.T) {
+	transport, client := NewMockClient(
+		AllowHosts("example.com"),
+		WithPassthrough(&mockMockTransport{}),
+		WithNoResponder(NewStringResponder(404, "not found")),
+	)
+
+	resp, err := client.Get("http://example.com:8080")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "ok" {
+		t.Errorf("Unexpected body from passthrough transport: %s", data)
+	}
+
+	resp, err = client.Get(testURL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 404 {
+		t.Errorf("Expected no responder to be used, got status %d", resp.StatusCode)
+	}
+
+	if transport.AllStubsCalled() != nil {
+		t.Errorf("Expected no error as no stubs were registered")
+	}
+}
+
+func TestMockTransportCallCounts(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest("GET", testURL, NewStringResponder(503, "unavailable"), Once()),
+		NewStubRequest("GET", testURL, NewStringResponder(200, "ok"), Times(2)),
+	)
+
+	for _, expected := range []int{503, 200, 200} {
+		resp, err := client.Get(testURL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != expected {
+			t.Errorf("Unexpected status, expected %d, got %d", expected, resp.StatusCode)
+		}
+	}
+
+	if err := transport.AllStubsCalled(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// all stubs are now exhausted, so this request should fail
+	_, err := client.Get(testURL)
+	if err == nil {
+		t.Fatal("Expected error once all stubs exhausted")
+	}
+
+	if !strings.Contains(err.Error(), "stub exhausted, already called") {
+		t.Errorf("Expected exhausted stub error, got: %s", err)
+	}
+
+	err = transport.AllStubsCalled()
+	if err == nil {
+		t.Fatal("Expected error as stub was called too many times")
+	}
+
+	if !strings.Contains(err.Error(), "expected exactly 1 call, got 2") {
+		t.Errorf("Expected call count in error, got: %s", err)
+	}
+}
+
+func TestMockTransportReplaysBody(t *testing.T) {
+	transport, client := NewMockClient()
+
+	echo := func(req *http.Request) (*http.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		return NewBytesResponse(200, body), nil
+	}
+
+	transport.RegisterStubRequests(
+		NewStubRequest("POST", testURL, NewStringResponder(200, "first"), WithBody(bytes.NewBufferString("first"))),
+		NewStubRequest("POST", testURL, echo, WithBody(bytes.NewBufferString("second"))),
+	)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(testURL, "text/plain", bytes.NewBufferString("second"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(body) != "second" {
+			t.Errorf("Expected responder to receive the full request body, got '%s'", body)
+		}
+	}
+}
+
+func TestMockTransportConcurrency(t *testing.T) {
+	transport, client := NewMockClient(WithChaos(Chaos{Seed: 1, LatencyRate: 0.1, MaxLatency: time.Millisecond}))
+
+	limited := NewStubRequest("GET", "http://example.com/limited", NewStringResponder(200, "limited"), Times(50))
+	transport.RegisterStubRequests(
+		limited,
+		NewStubRequest("POST", "http://example.com/users", NewStringResponder(201, "created"), WithBody(bytes.NewBufferString("name=bob"))),
+		NewStubRequest("GET", "http://example.com/articles", NewStringResponder(200, "articles"), WithScenario("articles", ScenarioStarted, "listed")),
+		NewStubRequest("GET", "http://example.com/articles", NewStringResponder(200, "articles"), WithScenario("articles", "listed", ScenarioStarted)),
+	)
+
+	const workers = 10
+	const requests = 20
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < requests; i++ {
+				var resp *http.Response
+				var err error
+
+				switch i % 4 {
+				case 0:
+					resp, err = client.Get("http://example.com/limited")
+				case 1:
+					resp, err = client.Post("http://example.com/users", "text/plain", bytes.NewBufferString("name=bob"))
+				case 2:
+					resp, err = client.Get("http://example.com/articles")
+				default:
+					resp, err = client.Get("http://example.com/missing")
+				}
+
+				if err == nil {
+					ioutil.ReadAll(resp.Body)
+					resp.Body.Close()
+				}
+			}
+		}(w)
+	}
+
+	// inspect and reconfigure the transport while requests are in flight
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < requests; i++ {
+			transport.Journal()
+			transport.StubRequests()
+			transport.UnmatchedRequests()
+			transport.AllStubsCalled()
+			transport.Scenarios()
+			limited.CallCount()
+			limited.Journal()
+
+			stub := NewStubRequest("GET", "http://example.com/temporary", NewStringResponder(200, "ok"))
+			transport.RegisterStubRequests(stub)
+			transport.RemoveStubRequest(stub)
+			transport.RegisterNoResponder(nil)
+			transport.SetChaos(&Chaos{Seed: int64(i)})
+		}
+	}()
+
+	wg.Wait()
+
+	if len(transport.Journal()) != workers*requests {
+		t.Errorf("Expected %d journal entries, got %d", workers*requests, len(transport.Journal()))
+	}
+
+	if calls := limited.CallCount(); calls != workers*requests/4 {
+		t.Errorf("Expected %d calls to limited stub, got %d", workers*requests/4, calls)
+	}
+
+	if len(limited.Journal()) != 50 {
+		t.Errorf("Expected 50 journal entries for limited stub, got %d", len(limited.Journal()))
+	}
+
+	if len(transport.UnmatchedRequests()) != workers*requests/4 {
+		t.Errorf("Expected %d unmatched requests, got %d", workers*requests/4, len(transport.UnmatchedRequests()))
+	}
+
+	transport.Reset()
+	if len(transport.Journal()) != 0 {
+		t.Errorf("Expected empty journal after reset")
+	}
+}
+
+func TestMockTransportReplaceStubRequests(t *testing.T) {
+	transport, client := NewMockClient()
+
+	a := NewStubRequest("GET", "http://example.com/a", NewStringResponder(200, "a"))
+	b := NewStubRequest("GET", "http://example.com/b", NewStringResponder(200, "b"))
+	c := NewStubRequest("GET", "http://example.com/c", NewStringResponder(200, "c"))
+	transport.RegisterStubRequests(a, b, c)
+
+	resp, err := client.Get("http://example.com/a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	d := NewStubRequest("GET", "http://example.com/b", NewStringResponder(200, "d"))
+	e := NewStubRequest("GET", "http://example.com/e", NewStringResponder(200, "e"))
+	transport.ReplaceStubRequests([]*StubRequest{b}, d, e)
+
+	expected := []*StubRequest{a, d, e, c}
+	if stubs := transport.StubRequests(); !reflect.DeepEqual(stubs, expected) {
+		t.Errorf("Unexpected stubs after replace, expected %v, got %v", expected, stubs)
+	}
+
+	if len(transport.Journal()) != 1 {
+		t.Errorf("Expected journal to be kept, got %d entries", len(transport.Journal()))
+	}
+
+	// stubs no longer registered are added after every other stub
+	f := NewStubRequest("GET", "http://example.com/f", NewStringResponder(200, "f"))
+	transport.ReplaceStubRequests([]*StubRequest{b}, f)
+
+	expected = []*StubRequest{a, d, e, c, f}
+	if stubs := transport.StubRequests(); !reflect.DeepEqual(stubs, expected) {
+		t.Errorf("Unexpected stubs after replace, expected %v, got %v", expected, stubs)
+	}
+}
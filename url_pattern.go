@@ -0,0 +1,216 @@
+package simular
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// regexpURLPrefix is the prefix used to mark a StubRequest URL as a regular
+// expression rather than a literal URL or template.
+const regexpURLPrefix = "=~"
+
+// templateToken matches the parts of a URL template that are not literal,
+// i.e. named parameters such as {id} and the glob wildcards * and **.
+var templateToken = regexp.MustCompile(`\{[A-Za-z_][A-Za-z0-9_]*\}|\*\*|\*`)
+
+// pathParamsKey is the context key used to store the path parameters captured
+// from a request URL.
+type pathParamsKey struct{}
+
+// PathParams returns the parameters captured from the request URL by the
+// template or regular expression URL of the stub that matched it. For
+// example a stub with the URL https://api.example.com/users/{id} matching a
+// request for https://api.example.com/users/123 gives the Responder a request
+// for which PathParams returns {"id": "123"}. Named groups of a regular
+// expression URL are captured in the same way.
+func PathParams(req *http.Request) map[string]string {
+	params, _ := req.Context().Value(pathParamsKey{}).(map[string]string)
+	return params
+}
+
+// PathParam returns a single named parameter captured from the request URL,
+// or an empty string if there is no such parameter.
+func PathParam(req *http.Request, name string) string {
+	return PathParams(req)[name]
+}
+
+// withPathParams returns a copy of the request carrying the given parameters
+// in its context.
+func withPathParams(req *http.Request, params map[string]string) *http.Request {
+	if len(params) == 0 {
+		return req
+	}
+
+	return req.WithContext(context.WithValue(req.Context(), pathParamsKey{}, params))
+}
+
+// urlPattern is a stub URL pattern compiled to a regular expression
+type urlPattern struct {
+	// url is the stub URL the pattern was compiled from
+	url    string
+	regexp *regexp.Regexp
+}
+
+// isURLPattern returns true if the given stub URL is a template, glob or
+// regular expression rather than a literal URL. Templates and globs are only
+// recognised in the path, so literal query strings such as ?fields=* are
+// still compared literally.
+func isURLPattern(url string) bool {
+	path, _ := splitPatternQuery(url)
+	return strings.HasPrefix(url, regexpURLPrefix) || templateToken.MatchString(path)
+}
+
+// splitPatternQuery splits a stub URL into the part before any query string,
+// and the query string itself including the leading ?.
+func splitPatternQuery(url string) (string, string) {
+	if i := strings.Index(url, "?"); i >= 0 {
+		return url[:i], url[i:]
+	}
+	return url, ""
+}
+
+// compileURLPattern converts a stub URL pattern into a regular expression
+// matched against normalized request URLs. URLs prefixed with =~ are used as
+// regular expressions directly. Otherwise the URL is treated as a template in
+// which {name} matches a single path segment captured as a parameter, *
+// matches any part of a single path segment and ** matches anything. The rest
+// of the template, including any query string, is normalized in the same way
+// as literal URLs.
+func compileURLPattern(url string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(url, regexpURLPrefix) {
+		return regexp.Compile(strings.TrimPrefix(url, regexpURLPrefix))
+	}
+
+	// swap each token in the path for a placeholder that survives
+	// normalization
+	path, query := splitPatternQuery(url)
+	tokens := templateToken.FindAllString(path, -1)
+	i := 0
+	placeholdered := templateToken.ReplaceAllStringFunc(path, func(string) string {
+		placeholder := fmt.Sprintf("simularparam%dx", i)
+		i++
+		return placeholder
+	}) + query
+
+	normalized, err := normalizeURL(placeholdered)
+	if err != nil {
+		return nil, err
+	}
+
+	expr := regexp.QuoteMeta(normalized)
+	for i, token := range tokens {
+		var group string
+		switch token {
+		case "**":
+			group = `.*`
+		case "*":
+			group = `[^/?#]*`
+		default:
+			group = fmt.Sprintf(`(?P<%s>[^/?#]+)`, strings.Trim(token, "{}"))
+		}
+
+		expr = strings.Replace(expr, fmt.Sprintf("simularparam%dx", i), group, 1)
+	}
+
+	return regexp.Compile("^" + expr + "$")
+}
+
+// compileURL compiles the stub's URL if it is a pattern, so that it isn't
+// recompiled for every request. An error is returned if the pattern is
+// invalid.
+func (r *StubRequest) compileURL() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.URL == "" || !isURLPattern(r.URL) {
+		r.pattern = nil
+		return nil
+	}
+
+	if r.pattern != nil && r.pattern.url == r.URL {
+		return nil
+	}
+
+	pattern, err := compileURLPattern(r.URL)
+	if err != nil {
+		return fmt.Errorf("Invalid URL pattern %s: %s", r.URL, err)
+	}
+
+	r.pattern = &urlPattern{url: r.URL, regexp: pattern}
+
+	return nil
+}
+
+// urlPattern returns the compiled URL pattern of the stub. Patterns are
+// compiled when the stub is created or registered, but we compile the URL
+// here if it has since been changed.
+func (r *StubRequest) urlPattern() (*regexp.Regexp, error) {
+	r.mu.Lock()
+	pattern := r.pattern
+	r.mu.Unlock()
+
+	if pattern != nil && pattern.url == r.URL {
+		return pattern.regexp, nil
+	}
+
+	compiled, err := compileURLPattern(r.URL)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid URL pattern %s: %s", r.URL, err)
+	}
+
+	return compiled, nil
+}
+
+// matchURLPattern matches the request URL against the stub's URL pattern,
+// returning the captured parameters. If the stub has query options the
+// pattern is matched against the URL without its query string.
+func (r *StubRequest) matchURLPattern(req *http.Request) (map[string]string, error) {
+	pattern, err := r.urlPattern()
+	if err != nil {
+		return nil, err
+	}
+
+	// query options take over matching the query string, so we exclude it
+	// from the URL being matched
+	var normalizedReqURL string
+	if len(r.queryMatchers) == 0 {
+		normalizedReqURL, err = normalizeURL(req.URL.String())
+	} else {
+		normalizedReqURL, _, err = splitQuery(req.URL.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	match := pattern.FindStringSubmatch(normalizedReqURL)
+	if match == nil {
+		return nil, mismatchf(MismatchURL, "", r.URL, normalizedReqURL, "Unexpected URL, expected %s, got %s", r.URL, normalizedReqURL)
+	}
+
+	params := map[string]string{}
+	for i, name := range pattern.SubexpNames() {
+		if name != "" {
+			params[name] = match[i]
+		}
+	}
+
+	return params, nil
+}
+
+// pathParams returns the parameters captured from the request URL by the
+// stub's URL pattern, if it has one.
+func (r *StubRequest) pathParams(req *http.Request) map[string]string {
+	if !isURLPattern(r.URL) {
+		return nil
+	}
+
+	params, err := r.matchURLPattern(req)
+	if err != nil {
+		return nil
+	}
+
+	return params
+}
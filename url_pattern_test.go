@@ -0,0 +1,160 @@
+package simular
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchesURLPattern(t *testing.T) {
+	testcases := []struct {
+		stubURL        string
+		options        []Option
+		requestURL     string
+		expectedParams map[string]string
+		expectedErr    bool
+	}{
+		{
+			stubURL:        "https://api.example.com/users/{id}/orders/{orderID}",
+			requestURL:     "https://API.example.com/users/123/orders/abc",
+			expectedParams: map[string]string{"id": "123", "orderID": "abc"},
+		},
+		{
+			stubURL:     "https://api.example.com/users/{id}",
+			requestURL:  "https://api.example.com/users/123/orders",
+			expectedErr: true,
+		},
+		{
+			stubURL:     "https://api.example.com/users/{id}",
+			requestURL:  "http://api.example.com/users/123",
+			expectedErr: true,
+		},
+		{
+			stubURL:        "api.example.com/files/*.json",
+			requestURL:     "http://api.example.com/files/data.json",
+			expectedParams: map[string]string{},
+		},
+		{
+			stubURL:     "api.example.com/files/*.json",
+			requestURL:  "http://api.example.com/files/nested/data.json",
+			expectedErr: true,
+		},
+		{
+			stubURL:        "api.example.com/files/**",
+			requestURL:     "http://api.example.com/files/nested/data.json",
+			expectedParams: map[string]string{},
+		},
+		{
+			stubURL:        `=~^https://api\.example\.com/users/(?P<id>\d+)$`,
+			requestURL:     "https://api.example.com/users/42",
+			expectedParams: map[string]string{"id": "42"},
+		},
+		{
+			stubURL:     `=~^https://api\.example\.com/users/(?P<id>\d+)$`,
+			requestURL:  "https://api.example.com/users/me",
+			expectedErr: true,
+		},
+		{
+			stubURL:        "https://api.example.com/users/{id}",
+			options:        []Option{WithQueryParams(url.Values{"api_key": {"X"}})},
+			requestURL:     "https://api.example.com/users/1?page=2&api_key=X",
+			expectedParams: map[string]string{"id": "1"},
+		},
+		{
+			stubURL:     "https://api.example.com/users/{id}",
+			options:     []Option{WithQueryParams(url.Values{"api_key": {"X"}})},
+			requestURL:  "https://api.example.com/users/1?page=2",
+			expectedErr: true,
+		},
+		{
+			stubURL:    "https://api.example.com/users?fields=*",
+			requestURL: "https://api.example.com/users?fields=*",
+		},
+		{
+			stubURL:     "https://api.example.com/users?fields=*",
+			requestURL:  "https://api.example.com/users?fields=name",
+			expectedErr: true,
+		},
+		{
+			stubURL:        "https://api.example.com/users/{id}?fields=*",
+			requestURL:     "https://api.example.com/users/1?fields=*",
+			expectedParams: map[string]string{"id": "1"},
+		},
+		{
+			stubURL:     "https://api.example.com/users/{id}?fields=*",
+			requestURL:  "https://api.example.com/users/1?fields=name",
+			expectedErr: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(fmt.Sprintf("%s %s", testcase.stubURL, testcase.requestURL), func(t *testing.T) {
+			stub := NewStubRequest("GET", testcase.stubURL, NewStringResponder(200, "ok"), testcase.options...)
+
+			req, err := http.NewRequest("GET", testcase.requestURL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = stub.Matches(req)
+			if testcase.expectedErr {
+				if err == nil {
+					t.Errorf("Expected error, got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error, got %s", err)
+			}
+
+			params := stub.pathParams(req)
+			if !reflect.DeepEqual(params, testcase.expectedParams) {
+				t.Errorf("Unexpected params, expected %v, got %v", testcase.expectedParams, params)
+			}
+		})
+	}
+}
+
+func TestMockTransportPathParams(t *testing.T) {
+	transport, client := NewMockClient()
+
+	transport.RegisterStubRequests(
+		NewStubRequest(
+			"GET",
+			"https://api.example.com/users/{id}/orders/{orderID}",
+			func(req *http.Request) (*http.Response, error) {
+				return NewStringResponse(200, PathParam(req, "id")+":"+PathParam(req, "orderID")), nil
+			},
+		),
+	)
+
+	resp, err := client.Get("https://api.example.com/users/7/orders/99")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "7:99" {
+		t.Errorf("Unexpected body, expected path params, got %s", body)
+	}
+}
+
+func TestInvalidURLPattern(t *testing.T) {
+	transport, client := NewMockClient()
+	transport.RegisterStubRequests(NewStubRequest("GET", "=~^https://api.example.com/(", NewStringResponder(200, "ok")))
+
+	_, err := client.Get("https://api.example.com/")
+	if err == nil || !strings.Contains(err.Error(), "Invalid URL pattern =~^https://api.example.com/(") {
+		t.Errorf("Expected invalid URL pattern error, got %v", err)
+	}
+}
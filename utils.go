@@ -1,6 +1,10 @@
 package simular
 
 import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
 	"github.com/goware/urlx"
 )
 
@@ -30,3 +34,45 @@ func contains(values []string, value string) bool {
 
 	return false
 }
+
+// cloneHeader returns a deep copy of the given http.Header
+func cloneHeader(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+
+	clone := make(http.Header, len(header))
+	for k, v := range header {
+		clone[k] = append([]string(nil), v...)
+	}
+
+	return clone
+}
+
+// readBody reads the body of the given request, replacing it with a fresh
+// reader over the same bytes so that it can be read again.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resetBody(req, body)
+
+	return body, nil
+}
+
+// resetBody replaces the body of the given request with a fresh reader over
+// the given bytes. A nil body leaves the request untouched.
+func resetBody(req *http.Request, body []byte) {
+	if body == nil {
+		return
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+}